@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// broadcast and deleted txs are moved here, outside of the chain/key directories
+	archiveDir = "archive"
+
+	// record of why and by whom a tx was archived, written next to the archived files
+	archiveRecordJSON = "archive.json"
+
+	// format of the timestamp used to name archived txs without a tx hash
+	archiveTimeFormat = "20060102T150405.000Z"
+)
+
+// ArchiveRecord describes what happened to an archived tx
+type ArchiveRecord struct {
+	Chain       string `json:"chain"`
+	Key         string `json:"key"`
+	Index       int    `json:"index"`
+	Action      string `json:"action"` // broadcast or delete
+	TxHash      string `json:"txhash,omitempty"`
+	Code        int    `json:"code"`
+	By          string `json:"by"`
+	Time        string `json:"time"`
+	Description string `json:"description,omitempty"`
+}
+
+// move the files of a tx to archive/<chain>/<key>/<txhash or timestamp>/ along with
+// the record, then prune the archive of that chain/key pair if a retention is configured
+func archiveTx(sess *session.Session, conf *Config, txDir string, fileNames []string, record ArchiveRecord) error {
+	now := time.Now().UTC()
+	record.By = conf.User
+	record.Time = now.Format(time.RFC3339)
+
+	dstDir, err := newArchiveDir(sess, conf, record, now)
+	if err != nil {
+		return err
+	}
+
+	// copy everything first, so nothing is lost if we fail half way
+	for _, f := range fileNames {
+		if err := awsCopy(sess, conf.AWS, filepath.Join(txDir, f), filepath.Join(dstDir, f)); err != nil {
+			return fmt.Errorf("cannot archive %s: %s", filepath.Join(txDir, f), err)
+		}
+	}
+	recordBytes, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := awsUpload(sess, conf.AWS, dstDir, archiveRecordJSON, recordBytes); err != nil {
+		return err
+	}
+
+	for _, f := range fileNames {
+		if err := awsDelete(sess, conf.AWS, filepath.Join(txDir, f)); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("archived %d files from %s to %s\n", len(fileNames), txDir, dstDir)
+
+	if conf.ArchiveDays > 0 {
+		if err := pruneArchive(sess, conf, record.Chain, record.Key, now.AddDate(0, 0, -conf.ArchiveDays)); err != nil {
+			fmt.Printf("WARNING: cannot prune the archive of %s/%s: %s\n", record.Chain, record.Key, err)
+		}
+	}
+	return nil
+}
+
+// a directory of the archive of a chain/key pair nothing was archived in yet, named after the tx hash.
+// Txs without a tx hash, or whose tx hash was already archived, are named after the time, index and
+// a random suffix, so two txs archived at the same time by different members don't overwrite each other
+func newArchiveDir(sess *session.Session, conf *Config, record ArchiveRecord, now time.Time) (string, error) {
+	suffixBytes := make([]byte, 4)
+	if _, err := rand.Read(suffixBytes); err != nil {
+		return "", err
+	}
+	stamp := fmt.Sprintf("%s-%d-%s", now.Format(archiveTimeFormat), record.Index, hex.EncodeToString(suffixBytes))
+
+	ids := []string{stamp}
+	if record.TxHash != "" {
+		ids = []string{record.TxHash, record.TxHash + "-" + stamp}
+	}
+	for _, id := range ids {
+		dir := filepath.Join(archiveDir, record.Chain, record.Key, id)
+		objects, err := awsListObjects(sess, conf.AWS, dir+"/")
+		if err != nil {
+			return "", fmt.Errorf("cannot check whether %s is already archived: %s", dir, err)
+		}
+		if len(objects) == 0 {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("%s is already archived, not overwriting it", filepath.Join(archiveDir, record.Chain, record.Key, ids[len(ids)-1]))
+}
+
+// the chain, key and index of a tx directory (<chain>/<key>/<index>)
+func parseTxDir(txDir string) (string, string, int, error) {
+	spl := strings.Split(filepath.Clean(txDir), "/")
+	if len(spl) != 3 {
+		return "", "", 0, fmt.Errorf("expected <chain>/<key>/<index>, got %s", txDir)
+	}
+	index, err := strconv.Atoi(spl[2])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid index in %s", txDir)
+	}
+	return spl[0], spl[1], index, nil
+}
+
+// an archived tx, with the objects it is made of
+type archiveEntry struct {
+	Dir      string
+	Files    []string
+	Modified time.Time
+}
+
+// list the archived txs under a prefix of the archive, oldest first
+func listArchive(sess *session.Session, conf *Config, prefix string) ([]*archiveEntry, error) {
+	objects, err := awsListObjects(sess, conf.AWS, strings.TrimSuffix(prefix, "/")+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*archiveEntry{}
+	for _, item := range objects {
+		// archive/<chain>/<key>/<id>/<file>
+		spl := strings.Split(*item.Key, "/")
+		if len(spl) != 5 || spl[4] == "" {
+			continue
+		}
+		dir := strings.Join(spl[:4], "/")
+		entry, found := entries[dir]
+		if !found {
+			entry = &archiveEntry{Dir: dir}
+			entries[dir] = entry
+		}
+		entry.Files = append(entry.Files, spl[4])
+		if item.LastModified != nil && item.LastModified.After(entry.Modified) {
+			entry.Modified = *item.LastModified
+		}
+	}
+
+	list := []*archiveEntry{}
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Modified.Before(list[j].Modified)
+	})
+	return list, nil
+}
+
+// remove the archived txs of a chain/key pair older than cutoff
+func pruneArchive(sess *session.Session, conf *Config, chainName, keyName string, cutoff time.Time) error {
+	entries, err := listArchive(sess, conf, filepath.Join(archiveDir, chainName, keyName))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Modified.After(cutoff) {
+			continue
+		}
+		for _, f := range entry.Files {
+			if err := awsDelete(sess, conf.AWS, filepath.Join(entry.Dir, f)); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("pruned %s from the archive\n", entry.Dir)
+	}
+	return nil
+}
+
+// list the archived txs, optionally only those of a chain or chain/key pair
+func cmdArchiveList(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	prefix := filepath.Join(append([]string{archiveDir}, args...)...)
+	entries, err := listArchive(sess, conf, prefix)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("no archived txs in %s\n", prefix)
+		return nil
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s  %s  (%d files)\n", entry.Modified.UTC().Format(time.RFC3339), strings.TrimPrefix(entry.Dir, archiveDir+"/"), len(entry.Files))
+	}
+	return nil
+}
+
+// show what was signed, by whom, and what happened to an archived tx
+func cmdArchiveShow(cmd *cobra.Command, args []string) error {
+	chainName := args[0]
+	keyName := args[1]
+	id := args[2]
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	dir := filepath.Join(archiveDir, chainName, keyName, id)
+	entries, err := listArchive(sess, conf, dir)
+	if err != nil {
+		return err
+	}
+	var entry *archiveEntry
+	for _, e := range entries {
+		if e.Dir == dir {
+			entry = e
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("%s not found in the archive", dir)
+	}
+
+	sep := "----------------------------------------"
+	for _, name := range []string{archiveRecordJSON, signDataJSON, manifestJSON, unsignedJSON} {
+		b, err := awsDownloadBytes(sess, conf.AWS, dir, name)
+		if err != nil {
+			continue
+		}
+		fmt.Println(sep)
+		fmt.Println(name)
+		fmt.Println(sep)
+		fmt.Println(strings.TrimSpace(string(b)))
+	}
+
+	fmt.Println(sep)
+	fmt.Println("signatures")
+	fmt.Println(sep)
+	for _, f := range entry.Files {
+		switch f {
+		case archiveRecordJSON, signDataJSON, manifestJSON, unsignedJSON:
+			continue
+		}
+		fmt.Println(f)
+	}
+	return nil
+}
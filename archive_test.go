@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestArchiveTx(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	conf := &Config{User: "me", AWS: bucket}
+	sess := awsSession(conf.AWS)
+
+	// archive a tx of each index twice, like two members deleting or broadcasting in the same second
+	archive := func(index int, txHash string) {
+		txDir := filepath.Join("cosmoshub", "validator", "0")
+		objects[txDir+"/unsigned.json"] = []byte(`{"index":` + strconv.Itoa(index) + `}`)
+		record := ArchiveRecord{Chain: "cosmoshub", Key: "validator", Index: index, Action: "delete", TxHash: txHash}
+		if err := archiveTx(sess, conf, txDir, []string{"unsigned.json"}, record); err != nil {
+			t.Fatal(err)
+		}
+		if _, found := objects[txDir+"/unsigned.json"]; found {
+			t.Fatalf("%s was not removed", txDir)
+		}
+	}
+	archive(0, "")
+	archive(0, "")
+	archive(1, "ABCD")
+	archive(1, "ABCD")
+
+	dirs := map[string]bool{}
+	for name, b := range objects {
+		if !strings.HasSuffix(name, "/"+archiveRecordJSON) {
+			continue
+		}
+		dir := strings.TrimSuffix(name, "/"+archiveRecordJSON)
+		dirs[dir] = true
+		var record ArchiveRecord
+		if err := json.Unmarshal(b, &record); err != nil || record.By != "me" {
+			t.Fatalf("got record %s and %v in %s", b, err, dir)
+		}
+		if _, found := objects[dir+"/unsigned.json"]; !found {
+			t.Fatalf("%s has no unsigned.json", dir)
+		}
+	}
+	if len(dirs) != 4 {
+		t.Fatalf("got archive dirs %v, want 4 distinct ones", dirs)
+	}
+	if !dirs["archive/cosmoshub/validator/ABCD"] {
+		t.Fatalf("got archive dirs %v, want one named after the tx hash", dirs)
+	}
+}
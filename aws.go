@@ -2,12 +2,15 @@ package main
 
 import (
 	"bytes"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/processcreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -15,21 +18,23 @@ import (
 
 // setup a new aws session
 func awsSession(conf AWS) *session.Session {
-	var sess *session.Session
-	var err error
+	config := aws.Config{
+		Region: aws.String(conf.BucketRegion),
+	}
 	if conf.Address != "" {
-		sess, err = session.NewSession(&aws.Config{
-			Endpoint:         aws.String(conf.Address),
-			Region:           aws.String(conf.BucketRegion),
-			Credentials:      credentials.NewStaticCredentials(conf.Pub, conf.Priv, ""),
-			S3ForcePathStyle: aws.Bool(true),
-		})
-	} else {
-		sess, err = session.NewSession(&aws.Config{
-			Region:      aws.String(conf.BucketRegion),
-			Credentials: credentials.NewStaticCredentials(conf.Pub, conf.Priv, ""),
-		})
+		config.Endpoint = aws.String(conf.Address)
+		config.S3ForcePathStyle = aws.Bool(true)
 	}
+	config.Credentials = awsCredentials(conf)
+
+	// with no credentials set in the config, the session uses the standard aws credential chain:
+	// env vars, ~/.aws/credentials and ~/.aws/config profiles (including web identity,
+	// sso and credential_process), and container or instance roles
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		Profile:           conf.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
 	if err != nil {
 		// TODO
 		panic(err)
@@ -37,8 +42,23 @@ func awsSession(conf AWS) *session.Session {
 	return sess
 }
 
+// credentials explicitly set in the config, or nil to use the standard aws credential chain
+func awsCredentials(conf AWS) *credentials.Credentials {
+	if conf.CredentialProcess != "" {
+		return processcreds.NewCredentials(conf.CredentialProcess)
+	}
+	if conf.Pub != "" && conf.Priv != "" {
+		return credentials.NewStaticCredentials(conf.Pub, conf.Priv, conf.SessionToken)
+	}
+	return nil
+}
+
 // download txDir/name from s3 bucket and save it to $PWD/name
 func awsDownload(sess *session.Session, conf AWS, txDir, name string) (*os.File, error) {
+	b, err := awsDownloadBytes(sess, conf, txDir, name)
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.Create(name)
 	if err != nil {
@@ -46,9 +66,25 @@ func awsDownload(sess *session.Session, conf AWS, txDir, name string) (*os.File,
 	}
 	defer file.Close()
 
-	downloader := s3manager.NewDownloader(sess)
+	if _, err := file.Write(b); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
 
-	numBytes, err := downloader.Download(file,
+// whether an error of a download is because the object doesn't exist
+func isNoSuchKey(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == s3.ErrCodeNoSuchKey
+}
+
+// download txDir/name from s3 bucket and return its contents, decrypted if need be
+func awsDownloadBytes(sess *session.Session, conf AWS, txDir, name string) ([]byte, error) {
+	buf := aws.NewWriteAtBuffer([]byte{})
+
+	downloader := s3manager.NewDownloader(sess)
+	_, err := downloader.Download(buf,
 		&s3.GetObjectInput{
 			Bucket: aws.String(conf.Bucket),
 			Key:    aws.String(filepath.Join(txDir, name)),
@@ -56,15 +92,19 @@ func awsDownload(sess *session.Session, conf AWS, txDir, name string) (*os.File,
 	if err != nil {
 		return nil, err
 	}
-	_ = numBytes
 
-	return file, nil
+	return decryptObject(conf.Encryption, buf.Bytes())
 }
 
-// upload the dataBytes to txDir/name in the s3 bucket
+// upload the dataBytes to txDir/name in the s3 bucket, encrypted if recipients are configured
 func awsUpload(sess *session.Session, conf AWS, txDir, name string, dataBytes []byte) error {
+	dataBytes, err := encryptObject(conf.Encryption, dataBytes)
+	if err != nil {
+		return err
+	}
+
 	uploader := s3manager.NewUploader(sess)
-	_, err := uploader.Upload(&s3manager.UploadInput{
+	_, err = uploader.Upload(&s3manager.UploadInput{
 		Bucket: aws.String(conf.Bucket),
 		Key:    aws.String(filepath.Join(txDir, name)),
 		Body:   bytes.NewBuffer(dataBytes),
@@ -93,21 +133,48 @@ func awsDelete(sess *session.Session, conf AWS, objName string) error {
 	return err
 }
 
-// download all files in the dir and return list of file names
-func awsDownloadFilesInDir(sess *session.Session, conf AWS, dirPath string) ([]string, error) {
+// list all objects whose key starts with prefix, across as many pages as needed
+func awsListObjects(sess *session.Session, conf AWS, prefix string) ([]*s3.Object, error) {
 	svc := s3.New(sess)
 
-	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.Bucket)})
+	objects := []*s3.Object{}
+	err := svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(conf.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		objects = append(objects, page.Contents...)
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
+	return objects, nil
+}
 
+// copy an object to a new key in the bucket
+func awsCopy(sess *session.Session, conf AWS, srcName, dstName string) error {
+	svc := s3.New(sess)
+	_, err := svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(conf.Bucket),
+		CopySource: aws.String(url.PathEscape(conf.Bucket + "/" + srcName)),
+		Key:        aws.String(dstName),
+	})
+	return err
+}
+
+// download all files in the dir and return list of file names
+func awsDownloadFilesInDir(sess *session.Session, conf AWS, dirPath string) ([]string, error) {
 	dirPath = strings.TrimSuffix(dirPath, "/")
 
-	// get only those in our folder
+	// list all items in our folder
+	objects, err := awsListObjects(sess, conf, dirPath+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	// get only those directly in our folder
 	files := []string{}
-	for _, item := range resp.Contents {
+	for _, item := range objects {
 		key := *item.Key
 		keyDir := filepath.Dir(key)
 		keyBase := strings.TrimPrefix(key, keyDir)
@@ -134,18 +201,16 @@ func awsDownloadFilesInDir(sess *session.Session, conf AWS, dirPath string) ([]s
 // return all files with prefix "chainName/keyName/"
 // eg. will return "chainName/keyName/foo" but not "chainName/keyName/" itself
 func awsListFilesInDir(sess *session.Session, conf AWS, chainName, keyName string) ([]string, error) {
-	svc := s3.New(sess)
-
 	filePath := filepath.Join(chainName, keyName) + "/"
 
-	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.Bucket)})
+	// list all items in our folder
+	objects, err := awsListObjects(sess, conf, filePath)
 	if err != nil {
 		return nil, err
 	}
 
 	files := []string{}
-	for _, item := range resp.Contents {
+	for _, item := range objects {
 		key := *item.Key
 		if strings.HasPrefix(key, filePath) && len(key) > len(filePath) {
 			files = append(files, key)
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+)
+
+// BalanceEntry is an amount of a denom held by a key on a chain, in one of the categories:
+// balance (spendable or not), delegation, unbonding (until the completion time),
+// vested (already vested by the vesting schedule of the account, since its start)
+// or vesting (still vesting in one period of the schedule, since its start until its end)
+type BalanceEntry struct {
+	Chain     string `json:"chain"`
+	Key       string `json:"key"`
+	Address   string `json:"address"`
+	Category  string `json:"category"`
+	Validator string `json:"validator,omitempty"`
+	Denom     string `json:"denom"`
+	Amount    string `json:"amount"`
+	Display   string `json:"display,omitempty"`
+	Since     string `json:"since,omitempty"`
+	Until     string `json:"until,omitempty"`
+}
+
+// response of the delegations query
+type delegationsResponse struct {
+	DelegationResponses []struct {
+		Delegation struct {
+			ValidatorAddress string `json:"validator_address"`
+		} `json:"delegation"`
+		Balance struct {
+			Denom  string `json:"denom"`
+			Amount string `json:"amount"`
+		} `json:"balance"`
+	} `json:"delegation_responses"`
+}
+
+// response of the unbonding delegations query, whose balances are in the bond denom
+type unbondingResponse struct {
+	UnbondingResponses []struct {
+		ValidatorAddress string `json:"validator_address"`
+		Entries          []struct {
+			CompletionTime string `json:"completion_time"`
+			Balance        string `json:"balance"`
+		} `json:"entries"`
+	} `json:"unbonding_responses"`
+}
+
+// a period of a vesting schedule, whose amount vests at End, or linearly from Start to End
+type vestingPeriod struct {
+	Start  time.Time // zero if the account has no start time
+	End    time.Time
+	Linear bool
+	Amount []sdk.Coin
+}
+
+// the vesting schedule of an account, and the coins it delegated while they were vesting
+type vestingSchedule struct {
+	Periods          []vestingPeriod
+	DelegatedVesting []sdk.Coin
+}
+
+// the amounts of a period already vested and still vesting at now
+func (p vestingPeriod) split(now time.Time) ([]sdk.Coin, []sdk.Coin) {
+	switch {
+	case !now.Before(p.End):
+		return p.Amount, nil
+	case !p.Linear || !now.After(p.Start):
+		return nil, p.Amount
+	}
+	elapsed := sdk.NewDec(int64(now.Sub(p.Start) / time.Second)).QuoInt64(int64(p.End.Sub(p.Start) / time.Second))
+	vested, vesting := []sdk.Coin{}, []sdk.Coin{}
+	for _, coin := range p.Amount {
+		amount := elapsed.MulInt(coin.Amount).TruncateInt()
+		vested = append(vested, sdk.Coin{Denom: coin.Denom, Amount: amount})
+		vesting = append(vesting, sdk.Coin{Denom: coin.Denom, Amount: coin.Amount.Sub(amount)})
+	}
+	return vested, vesting
+}
+
+// query the bond denom of a chain, from its staking params
+func queryBondDenom(client *ChainClient) (string, error) {
+	var params struct {
+		Params struct {
+			BondDenom string `json:"bond_denom"`
+		} `json:"params"`
+	}
+	if err := client.query("/cosmos/staking/v1beta1/params", &params); err != nil {
+		return "", fmt.Errorf("cannot query the staking params of %s: %s", client.chain.Name, err)
+	}
+	if params.Params.BondDenom == "" {
+		return "", fmt.Errorf("no bond denom in the staking params of %s", client.chain.Name)
+	}
+	return params.Params.BondDenom, nil
+}
+
+// parse the vesting schedule of an account, which is empty for accounts that aren't vesting
+func parseVestingSchedule(b []byte) (vestingSchedule, error) {
+	schedule := vestingSchedule{}
+	acctType, acct, err := unwrapAccount(b)
+	if err != nil {
+		return schedule, err
+	}
+
+	switch {
+	case strings.Contains(acctType, "StridePeriodicVestingAccount"):
+		var spva StridePeriodicVestingAccount
+		if err := json.Unmarshal(acct, &spva); err != nil {
+			return schedule, fmt.Errorf("failed to unmarshal stride periodic vesting account: %s", err)
+		}
+		for _, p := range spva.VestingPeriods {
+			start, err1 := strconv.ParseInt(p.StartTime, 10, 64)
+			length, err2 := strconv.ParseInt(p.Length, 10, 64)
+			if err1 != nil || err2 != nil {
+				return schedule, fmt.Errorf("invalid vesting period in stride periodic vesting account")
+			}
+			schedule.Periods = append(schedule.Periods, vestingPeriod{Start: time.Unix(start, 0), End: time.Unix(start+length, 0), Amount: toCoins(p.Amount)})
+		}
+		// the delegated vesting coins of stride accounts aren't typed
+		for _, d := range spva.BaseVestingAccount.DelegatedVesting {
+			if coin, ok := d.(map[string]interface{}); ok {
+				denom, _ := coin["denom"].(string)
+				amount, _ := coin["amount"].(string)
+				if a, ok := sdk.NewIntFromString(amount); ok {
+					schedule.DelegatedVesting = append(schedule.DelegatedVesting, sdk.Coin{Denom: denom, Amount: a})
+				}
+			}
+		}
+	case strings.Contains(acctType, "PeriodicVestingAccount"):
+		var pva PeriodicVestingAccount
+		if err := json.Unmarshal(acct, &pva); err != nil {
+			return schedule, fmt.Errorf("failed to unmarshal periodic vesting account: %s", err)
+		}
+		end, err := strconv.ParseInt(pva.StartTime, 10, 64)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid start time in periodic vesting account")
+		}
+		for _, p := range pva.VestingPeriods {
+			length, err := strconv.ParseInt(p.Length, 10, 64)
+			if err != nil {
+				return schedule, fmt.Errorf("invalid vesting period in periodic vesting account")
+			}
+			start := end
+			end += length
+			schedule.Periods = append(schedule.Periods, vestingPeriod{Start: time.Unix(start, 0), End: time.Unix(end, 0), Amount: toCoins(p.Amount)})
+		}
+		schedule.DelegatedVesting = toCoins(pva.BaseVestingAccount.DelegatedVesting)
+	case strings.Contains(acctType, "ContinuousVestingAccount"):
+		var cva ContinuousVestingAccount
+		if err := json.Unmarshal(acct, &cva); err != nil {
+			return schedule, fmt.Errorf("failed to unmarshal continuous vesting account: %s", err)
+		}
+		start, err1 := strconv.ParseInt(cva.StartTime, 10, 64)
+		end, err2 := strconv.ParseInt(cva.BaseVestingAccount.EndTime, 10, 64)
+		if err1 != nil || err2 != nil {
+			return schedule, fmt.Errorf("invalid start or end time in continuous vesting account")
+		}
+		schedule.Periods = append(schedule.Periods, vestingPeriod{Start: time.Unix(start, 0), End: time.Unix(end, 0), Linear: true, Amount: toCoins(cva.BaseVestingAccount.OriginalVesting)})
+		schedule.DelegatedVesting = toCoins(cva.BaseVestingAccount.DelegatedVesting)
+	case strings.Contains(acctType, "DelayedVestingAccount"):
+		var dva DelayedVestingAccount
+		if err := json.Unmarshal(acct, &dva); err != nil {
+			return schedule, fmt.Errorf("failed to unmarshal delayed vesting account: %s", err)
+		}
+		end, err := strconv.ParseInt(dva.BaseVestingAccount.EndTime, 10, 64)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid end time in delayed vesting account")
+		}
+		schedule.Periods = append(schedule.Periods, vestingPeriod{End: time.Unix(end, 0), Amount: toCoins(dva.BaseVestingAccount.OriginalVesting)})
+		schedule.DelegatedVesting = toCoins(dva.BaseVestingAccount.DelegatedVesting)
+	}
+	return schedule, nil
+}
+
+// the amounts of a vesting schedule vested at now, by denom, and the periods with the amounts still vesting.
+// The coins delegated while vesting are reported with the delegations, so they're left out of the
+// amounts still vesting, starting from the periods vesting last, which leaves the vesting coins of the balance
+func (s vestingSchedule) split(now time.Time) ([]sdk.Coin, []vestingPeriod) {
+	vested := []sdk.Coin{}
+	vestedIndex := map[string]int{}
+	vesting := []vestingPeriod{}
+	for _, period := range s.Periods {
+		periodVested, periodVesting := period.split(now)
+		for _, coin := range periodVested {
+			if i, found := vestedIndex[coin.Denom]; found {
+				vested[i].Amount = vested[i].Amount.Add(coin.Amount)
+			} else {
+				vestedIndex[coin.Denom] = len(vested)
+				vested = append(vested, coin)
+			}
+		}
+		if len(periodVesting) > 0 {
+			period.Amount = periodVesting
+			vesting = append(vesting, period)
+		}
+	}
+
+	delegated := map[string]sdk.Int{}
+	for _, coin := range s.DelegatedVesting {
+		delegated[coin.Denom] = coin.Amount
+	}
+	for i := len(vesting) - 1; i >= 0; i-- {
+		amount := []sdk.Coin{}
+		for _, coin := range vesting[i].Amount {
+			if d, found := delegated[coin.Denom]; found {
+				subtracted := sdk.MinInt(d, coin.Amount)
+				delegated[coin.Denom] = d.Sub(subtracted)
+				coin.Amount = coin.Amount.Sub(subtracted)
+			}
+			amount = append(amount, coin)
+		}
+		vesting[i].Amount = amount
+	}
+	return vested, vesting
+}
+
+// convert the denom/amount pairs of the account types to coins, skipping invalid amounts
+func toCoins(amounts []struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}) []sdk.Coin {
+	coins := []sdk.Coin{}
+	for _, a := range amounts {
+		amount, ok := sdk.NewIntFromString(a.Amount)
+		if !ok {
+			continue
+		}
+		coins = append(coins, sdk.Coin{Denom: a.Denom, Amount: amount})
+	}
+	return coins
+}
+
+// format a time of a vesting schedule, empty if it has none
+func formatVestingTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// query the balances, delegations, unbonding delegations and vesting schedule of a key on a chain
+func queryKeyBalances(client *ChainClient, key Key, assets AssetList, bondDenom string) ([]BalanceEntry, error) {
+	chain := client.chain
+	address, err := bech32ify(key.Address, chain.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries := []BalanceEntry{}
+	add := func(category, validator, denom, amount, since, until string) {
+		display := ""
+		if dec, err := sdk.NewDecFromStr(amount); err == nil {
+			display = displayAmount(assets, sdk.NewDecCoinFromDec(denom, dec))
+		}
+		entries = append(entries, BalanceEntry{
+			Chain: chain.Name, Key: key.Name, Address: address, Category: category, Validator: validator,
+			Denom: denom, Amount: amount, Display: display, Since: since, Until: until,
+		})
+	}
+
+	balances, err := client.Balances(address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query the balances of %s on %s: %s", key.Name, chain.Name, err)
+	}
+	for _, balance := range balances.Balances {
+		add("balance", "", balance.Denom, balance.Amount, "", "")
+	}
+
+	err = client.queryPages("/cosmos/staking/v1beta1/delegations/"+address, func(b []byte) error {
+		var delegations delegationsResponse
+		if err := json.Unmarshal(b, &delegations); err != nil {
+			return err
+		}
+		for _, d := range delegations.DelegationResponses {
+			add("delegation", d.Delegation.ValidatorAddress, d.Balance.Denom, d.Balance.Amount, "", "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot query the delegations of %s on %s: %s", key.Name, chain.Name, err)
+	}
+
+	err = client.queryPages("/cosmos/staking/v1beta1/delegators/"+address+"/unbonding_delegations", func(b []byte) error {
+		var unbonding unbondingResponse
+		if err := json.Unmarshal(b, &unbonding); err != nil {
+			return err
+		}
+		for _, u := range unbonding.UnbondingResponses {
+			for _, entry := range u.Entries {
+				add("unbonding", u.ValidatorAddress, bondDenom, entry.Balance, "", entry.CompletionTime)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot query the unbonding delegations of %s on %s: %s", key.Name, chain.Name, err)
+	}
+
+	// the vesting schedule is only there for vesting accounts
+	b, err := client.AccountJSON(address)
+	if errors.Is(err, errNotFound) {
+		// accounts which never received anything don't exist
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot query the account of %s on %s: %s", key.Name, chain.Name, err)
+	}
+	schedule, err := parseVestingSchedule(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the account of %s on %s: %s", key.Name, chain.Name, err)
+	}
+	if len(schedule.Periods) == 0 {
+		return entries, nil
+	}
+	vested, vesting := schedule.split(time.Now())
+	start := schedule.Periods[0].Start
+	for _, period := range schedule.Periods {
+		if period.Start.Before(start) {
+			start = period.Start
+		}
+	}
+	since := formatVestingTime(start)
+	for _, coin := range vested {
+		add("vested", "", coin.Denom, coin.Amount.String(), since, "")
+	}
+	for _, period := range vesting {
+		for _, coin := range period.Amount {
+			if coin.Amount.IsZero() {
+				continue
+			}
+			add("vesting", "", coin.Denom, coin.Amount.String(), formatVestingTime(period.Start), formatVestingTime(period.End))
+		}
+	}
+	return entries, nil
+}
+
+// report the balances, delegations, unbonding delegations and vesting schedules of every key on every chain
+func cmdBalances(cmd *cobra.Command, args []string) error {
+	if flagOutput != "table" && flagOutput != "csv" && flagOutput != "json" {
+		return fmt.Errorf("invalid --output %q, must be table, csv or json", flagOutput)
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	chains := conf.Chains
+	if len(args) > 0 {
+		chain, found := conf.GetChain(args[0])
+		if !found {
+			return fmt.Errorf("chain %s not found in config", args[0])
+		}
+		chains = []Chain{chain}
+	}
+	keys := conf.Keys
+	if len(args) > 1 {
+		key, found := conf.GetKey(args[1])
+		if !found {
+			return fmt.Errorf("key %s not found in config", args[1])
+		}
+		keys = []Key{key}
+	}
+
+	entries := []BalanceEntry{}
+	failed := 0
+	for _, chain := range chains {
+		if err := requireREST(chain); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s: %s\n", chain.Name, err)
+			continue
+		}
+		client := newChainClient(chain)
+		// amounts are shown in denoms only if the chain has no asset list
+		assets, _ := loadAssetList(conf, chain.Name)
+
+		bondDenom, err := queryBondDenom(client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s, unbonding delegations will have no denom\n", err)
+		}
+
+		for _, key := range keys {
+			keyEntries, err := queryKeyBalances(client, key, assets, bondDenom)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+				failed++
+				continue
+			}
+			entries = append(entries, keyEntries...)
+		}
+	}
+
+	switch flagOutput {
+	case "json":
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"chain", "key", "address", "category", "validator", "denom", "amount", "display", "since", "until"})
+		for _, e := range entries {
+			w.Write([]string{e.Chain, e.Key, e.Address, e.Category, e.Validator, e.Denom, e.Amount, e.Display, e.Since, e.Until})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHAIN/KEY\tCATEGORY\tVALIDATOR\tAMOUNT\tDISPLAY\tSINCE\tUNTIL")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s%s\t%s\t%s\t%s\n", e.Chain, e.Key, e.Category, orDash(e.Validator),
+				e.Amount, e.Denom, orDash(e.Display), orDash(e.Since), orDash(e.Until))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to query %d chain/key pairs, the report is incomplete", failed)
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestParseVestingSchedule(t *testing.T) {
+	cases := []struct {
+		name          string
+		body          string
+		wantPeriods   []vestingPeriod
+		wantDelegated string
+	}{
+		{
+			name: "base account",
+			body: `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"cosmos1abc","account_number":"1","sequence":"0"}}`,
+		},
+		{
+			name: "continuous",
+			body: `{"account":{"@type":"/cosmos.vesting.v1beta1.ContinuousVestingAccount","base_vesting_account":{"base_account":{"address":"cosmos1abc"},"original_vesting":[{"denom":"uatom","amount":"1000"}],"delegated_vesting":[{"denom":"uatom","amount":"300"}],"end_time":"2000"},"start_time":"1000"}}`,
+			wantPeriods: []vestingPeriod{
+				{Start: time.Unix(1000, 0), End: time.Unix(2000, 0), Linear: true, Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 1000)}},
+			},
+			wantDelegated: "300uatom",
+		},
+		{
+			name: "delayed",
+			body: `{"account":{"@type":"/cosmos.vesting.v1beta1.DelayedVestingAccount","base_vesting_account":{"base_account":{"address":"cosmos1abc"},"original_vesting":[{"denom":"uatom","amount":"1000"}],"end_time":"2000"}}}`,
+			wantPeriods: []vestingPeriod{
+				{End: time.Unix(2000, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 1000)}},
+			},
+		},
+		{
+			name: "amino periodic",
+			body: `{"account":{"type":"cosmos-sdk/PeriodicVestingAccount","value":{"base_vesting_account":{"base_account":{"address":"cosmos1abc"},"end_time":"1300"},"start_time":"1000","vesting_periods":[{"length":"100","amount":[{"denom":"uatom","amount":"10"}]},{"length":"200","amount":[{"denom":"uatom","amount":"20"}]}]}}}`,
+			wantPeriods: []vestingPeriod{
+				{Start: time.Unix(1000, 0), End: time.Unix(1100, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 10)}},
+				{Start: time.Unix(1100, 0), End: time.Unix(1300, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 20)}},
+			},
+		},
+		{
+			name: "stride periodic",
+			body: `{"account":{"@type":"/stride.vesting.StridePeriodicVestingAccount","base_vesting_account":{"base_account":{"address":"stride1abc"},"delegated_vesting":[{"denom":"ustrd","amount":"5"}]},"vesting_periods":[{"start_time":"1000","length":"100","amount":[{"denom":"ustrd","amount":"50"}],"action_type":0}]}}`,
+			wantPeriods: []vestingPeriod{
+				{Start: time.Unix(1000, 0), End: time.Unix(1100, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("ustrd", 50)}},
+			},
+			wantDelegated: "5ustrd",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := parseVestingSchedule([]byte(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(schedule.Periods) != len(tc.wantPeriods) {
+				t.Fatalf("got %d periods, want %d", len(schedule.Periods), len(tc.wantPeriods))
+			}
+			for i, got := range schedule.Periods {
+				want := tc.wantPeriods[i]
+				if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.Linear != want.Linear ||
+					sdk.Coins(got.Amount).String() != sdk.Coins(want.Amount).String() {
+					t.Fatalf("got period %d %+v, want %+v", i, got, want)
+				}
+			}
+			if got := sdk.Coins(schedule.DelegatedVesting).String(); got != tc.wantDelegated {
+				t.Fatalf("got delegated vesting %q, want %q", got, tc.wantDelegated)
+			}
+		})
+	}
+}
+
+func TestVestingScheduleSplit(t *testing.T) {
+	continuous := vestingPeriod{Start: time.Unix(1000, 0), End: time.Unix(2000, 0), Linear: true, Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 1000)}}
+	periodic := []vestingPeriod{
+		{Start: time.Unix(1000, 0), End: time.Unix(1100, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 10)}},
+		{Start: time.Unix(1100, 0), End: time.Unix(1300, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 20)}},
+		{Start: time.Unix(1300, 0), End: time.Unix(1600, 0), Amount: []sdk.Coin{sdk.NewInt64Coin("uatom", 30)}},
+	}
+	cases := []struct {
+		name        string
+		schedule    vestingSchedule
+		now         int64
+		wantVested  string
+		wantVesting []string // amounts still vesting per remaining period
+	}{
+		{name: "continuous before start", schedule: vestingSchedule{Periods: []vestingPeriod{continuous}}, now: 500, wantVested: "", wantVesting: []string{"1000uatom"}},
+		{name: "continuous halfway", schedule: vestingSchedule{Periods: []vestingPeriod{continuous}}, now: 1250, wantVested: "250uatom", wantVesting: []string{"750uatom"}},
+		{name: "continuous after end", schedule: vestingSchedule{Periods: []vestingPeriod{continuous}}, now: 2000, wantVested: "1000uatom"},
+		{
+			name:     "continuous with delegated vesting",
+			schedule: vestingSchedule{Periods: []vestingPeriod{continuous}, DelegatedVesting: []sdk.Coin{sdk.NewInt64Coin("uatom", 300)}},
+			now:      1250, wantVested: "250uatom", wantVesting: []string{"450uatom"},
+		},
+		{name: "periodic between periods", schedule: vestingSchedule{Periods: periodic}, now: 1200, wantVested: "10uatom", wantVesting: []string{"20uatom", "30uatom"}},
+		{
+			name:     "periodic with delegated vesting from the last periods",
+			schedule: vestingSchedule{Periods: periodic, DelegatedVesting: []sdk.Coin{sdk.NewInt64Coin("uatom", 40)}},
+			now:      1200, wantVested: "10uatom", wantVesting: []string{"10uatom", "0uatom"},
+		},
+		{
+			name:     "more delegated than vesting",
+			schedule: vestingSchedule{Periods: periodic, DelegatedVesting: []sdk.Coin{sdk.NewInt64Coin("uatom", 100)}},
+			now:      1200, wantVested: "10uatom", wantVesting: []string{"0uatom", "0uatom"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vested, vesting := tc.schedule.split(time.Unix(tc.now, 0))
+			if got := sdk.Coins(vested).String(); got != tc.wantVested {
+				t.Fatalf("got vested %q, want %q", got, tc.wantVested)
+			}
+			if len(vesting) != len(tc.wantVesting) {
+				t.Fatalf("got %d vesting periods, want %d", len(vesting), len(tc.wantVesting))
+			}
+			for i, period := range vesting {
+				if got := period.Amount[0].String(); got != tc.wantVesting[i] {
+					t.Fatalf("got %q vesting in period %d, want %q", got, i, tc.wantVesting[i])
+				}
+			}
+		})
+	}
+}
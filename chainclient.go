@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned by the chain client when the endpoint has nothing for the query,
+// eg. an account which never received anything, or a route the node doesn't serve
+var errNotFound = errors.New("not found")
+
+// ChainClient queries a chain directly through its rpc and rest endpoints,
+// so queries don't need the binary of the chain
+type ChainClient struct {
+	chain  Chain
+	client *http.Client
+}
+
+// TxResult is the outcome of a tx included in a block
+type TxResult struct {
+	TxHash string `json:"txhash"`
+	Height string `json:"height"`
+	Code   int    `json:"code"`
+	RawLog string `json:"raw_log"`
+}
+
+func newChainClient(chain Chain) *ChainClient {
+	return &ChainClient{chain: chain, client: NewHttpClient()}
+}
+
+// most queries go to the rest endpoint of a chain, which configs with only the deprecated node lack
+func requireREST(chain Chain) error {
+	if chain.REST == "" {
+		return fmt.Errorf("no rest endpoint for %s, set rest in its [[chains]] entry (the deprecated node is only used as the rpc endpoint)", chain.Name)
+	}
+	return nil
+}
+
+// GET a path on an endpoint, returning an error with the message of the node for non 2xx responses
+func (c *ChainClient) get(endpoint, name, path string) ([]byte, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("no %s endpoint for %s in the config", name, c.chain.Name)
+	}
+	// the binaries take tcp:// rpc addresses, which are served over http
+	if strings.HasPrefix(endpoint, "tcp://") {
+		endpoint = "http://" + strings.TrimPrefix(endpoint, "tcp://")
+	}
+	reqURL := strings.TrimSuffix(endpoint, "/") + path
+	res, err := c.client.Get(reqURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 == 2 {
+		return b, nil
+	}
+
+	var status struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	msg := strings.TrimSpace(string(b))
+	if json.Unmarshal(b, &status) == nil && (status.Message != "" || status.Error != "") {
+		msg = status.Message + status.Error
+	}
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusNotImplemented || strings.Contains(msg, "not found") {
+		return nil, fmt.Errorf("%w: %s: %s", errNotFound, reqURL, msg)
+	}
+	return nil, fmt.Errorf("%s: %s: %s", reqURL, res.Status, msg)
+}
+
+// query a path of the rest endpoint and parse the json response into v
+func (c *ChainClient) query(path string, v interface{}) error {
+	b, err := c.get(c.chain.REST, "rest", path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot parse the response of %s: %s", path, err)
+	}
+	return nil
+}
+
+// query every page of a paginated path of the rest endpoint, passing the json of each page to add.
+// Pages are requested with the next_key of the previous one until there is none
+func (c *ChainClient) queryPages(path string, add func(b []byte) error) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	nextKey := ""
+	for {
+		pagePath := path
+		if nextKey != "" {
+			pagePath += sep + "pagination.key=" + url.QueryEscape(nextKey)
+		}
+		b, err := c.get(c.chain.REST, "rest", pagePath)
+		if err != nil {
+			return err
+		}
+		if err := add(b); err != nil {
+			return fmt.Errorf("cannot parse the response of %s: %s", path, err)
+		}
+		var pagination struct {
+			Pagination struct {
+				NextKey string `json:"next_key"`
+			} `json:"pagination"`
+		}
+		if err := json.Unmarshal(b, &pagination); err != nil {
+			return fmt.Errorf("cannot parse the pagination of %s: %s", path, err)
+		}
+		if pagination.Pagination.NextKey == "" || pagination.Pagination.NextKey == nextKey {
+			return nil
+		}
+		nextKey = pagination.Pagination.NextKey
+	}
+}
+
+// the node info, with the versions of the app and its cosmos-sdk
+func (c *ChainClient) NodeInfo() (NodeInfo, error) {
+	var nodeInfo NodeInfo
+	if err := c.query("/cosmos/base/tendermint/v1beta1/node_info", &nodeInfo); err != nil {
+		return nodeInfo, fmt.Errorf("node info query failed: %s", err)
+	}
+	return nodeInfo, nil
+}
+
+// the chain-id the rpc endpoint is on
+func (c *ChainClient) Network() (string, error) {
+	b, err := c.get(c.chain.RPC, "rpc", "/status")
+	if err != nil {
+		return "", err
+	}
+	var status struct {
+		Result struct {
+			NodeInfo struct {
+				Network string `json:"network"`
+			} `json:"node_info"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(b, &status); err != nil {
+		return "", fmt.Errorf("cannot parse status response: %s", err)
+	}
+	if status.Result.NodeInfo.Network == "" {
+		return "", fmt.Errorf("cannot find the network in the status response")
+	}
+	return status.Result.NodeInfo.Network, nil
+}
+
+// the raw response of an account query, which wraps the account with its public key. Chains
+// without a rest endpoint are queried with their binary through the rpc endpoint
+func (c *ChainClient) AccountJSON(address string) ([]byte, error) {
+	if c.chain.REST == "" {
+		return c.queryBinary("query", "auth", "account", address)
+	}
+	return c.get(c.chain.REST, "rest", "/cosmos/auth/v1beta1/accounts/"+address)
+}
+
+// run a query with the binary of the chain through its rpc endpoint, returning its json output
+func (c *ChainClient) queryBinary(args ...string) ([]byte, error) {
+	if c.chain.RPC == "" {
+		return nil, fmt.Errorf("no rest or rpc endpoint for %s in the config", c.chain.Name)
+	}
+	args = append(args, "--node", c.chain.RPC, "--output", "json")
+	cmd := exec.Command(c.chain.Binary, args...)
+	b, err := cmd.Output()
+	if err != nil {
+		msg := err.Error()
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			msg = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		if strings.Contains(msg, "not found") {
+			return nil, fmt.Errorf("%w: %s: %s", errNotFound, cmd, msg)
+		}
+		return nil, fmt.Errorf("%s: %s", cmd, msg)
+	}
+	return b, nil
+}
+
+// the account in the response of an account query, with its @type: the rest endpoint and
+// the binary since cosmos-sdk v0.50 wrap it in an account object, either as proto json or as
+// amino json with the fields in a value object, the binary before v0.50 returns the account itself
+func unwrapAccount(b []byte) (string, []byte, error) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return "", nil, fmt.Errorf("cannot parse account: %s", err)
+	}
+	if inner, found := resp["account"]; found {
+		var amino struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(inner, &amino); err == nil && amino.Type != "" {
+			return amino.Type, amino.Value, nil
+		}
+		b = inner
+	}
+	var acctType AccountType
+	if err := json.Unmarshal(b, &acctType); err != nil {
+		return "", nil, fmt.Errorf("cannot parse account type: %s", err)
+	}
+	return acctType.Type, b, nil
+}
+
+// the account and sequence numbers of an address
+func (c *ChainClient) AccSeq(address string) (int, int, error) {
+	b, err := c.AccountJSON(address)
+	if err != nil {
+		return 0, 0, err
+	}
+	acctType, acct, err := unwrapAccount(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseAcctByType(acctType, acct)
+}
+
+// the bank balances of an address, from all the pages of the query
+func (c *ChainClient) Balances(address string) (AccountBalance, error) {
+	var ab AccountBalance
+	err := c.queryPages("/cosmos/bank/v1beta1/balances/"+address, func(b []byte) error {
+		var page AccountBalance
+		if err := json.Unmarshal(b, &page); err != nil {
+			return err
+		}
+		ab.Balances = append(ab.Balances, page.Balances...)
+		return nil
+	})
+	return ab, err
+}
+
+// the result of a tx, which is errNotFound until it's included in a block. Chains
+// without a rest endpoint are queried through the rpc endpoint
+func (c *ChainClient) Tx(hash string) (TxResult, error) {
+	if c.chain.REST == "" {
+		return c.rpcTx(hash)
+	}
+	var resp struct {
+		TxResponse TxResult `json:"tx_response"`
+	}
+	err := c.query("/cosmos/tx/v1beta1/txs/"+hash, &resp)
+	return resp.TxResponse, err
+}
+
+// the result of a tx from the tendermint rpc endpoint
+func (c *ChainClient) rpcTx(hash string) (TxResult, error) {
+	b, err := c.get(c.chain.RPC, "rpc", "/tx?hash=0x"+hash)
+	if err != nil {
+		return TxResult{}, err
+	}
+	var resp struct {
+		Result struct {
+			Hash     string `json:"hash"`
+			Height   string `json:"height"`
+			TxResult struct {
+				Code int    `json:"code"`
+				Log  string `json:"log"`
+			} `json:"tx_result"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return TxResult{}, fmt.Errorf("cannot parse the tx response: %s", err)
+	}
+	res := resp.Result
+	return TxResult{TxHash: res.Hash, Height: res.Height, Code: res.TxResult.Code, RawLog: res.TxResult.Log}, nil
+}
+
+// poll a tx until it's included in a block or the timeout is reached
+func (c *ChainClient) WaitTx(hash string, timeout time.Duration) (TxResult, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		res, err := c.Tx(hash)
+		if err == nil || !errors.Is(err, errNotFound) || time.Now().After(deadline) {
+			return res, err
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// check the grpc endpoint accepts connections. It isn't queried, but other tools
+// in the team may rely on it
+func (c *ChainClient) DialGRPC() error {
+	if c.chain.GRPC == "" {
+		return fmt.Errorf("no grpc endpoint for %s in the config", c.chain.Name)
+	}
+	host, port := c.chain.GRPC, "9090"
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, port)
+	}
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve a fixed status and body on every path
+func newTestServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChainClientGet(t *testing.T) {
+	cases := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantNotFound bool
+		wantInErr    string
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"ok":true}`},
+		{name: "404", status: http.StatusNotFound, body: `{"code":5,"message":"account cosmos1abc not found"}`, wantErr: true, wantNotFound: true},
+		{name: "501", status: http.StatusNotImplemented, body: `{"code":12,"message":"Not Implemented"}`, wantErr: true, wantNotFound: true},
+		{name: "not found message", status: http.StatusInternalServerError, body: `{"code":2,"message":"rpc error: key not found"}`, wantErr: true, wantNotFound: true},
+		{name: "not found error field", status: http.StatusBadRequest, body: `{"error":"tx not found: ABCD"}`, wantErr: true, wantNotFound: true},
+		{name: "other error", status: http.StatusInternalServerError, body: `{"code":13,"message":"internal error"}`, wantErr: true, wantInErr: "internal error"},
+		{name: "plain text error", status: http.StatusBadGateway, body: "bad gateway\n", wantErr: true, wantInErr: "bad gateway"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(t, tc.status, tc.body)
+			client := newChainClient(Chain{Name: "test", REST: srv.URL})
+
+			b, err := client.get(srv.URL, "rest", "/some/path")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if errors.Is(err, errNotFound) != tc.wantNotFound {
+				t.Fatalf("got error %v, want errNotFound %v", err, tc.wantNotFound)
+			}
+			if err != nil && !strings.Contains(err.Error(), tc.wantInErr) {
+				t.Fatalf("error %q doesn't contain %q", err, tc.wantInErr)
+			}
+			if err == nil && string(b) != tc.body {
+				t.Fatalf("got body %q, want %q", b, tc.body)
+			}
+		})
+	}
+}
+
+func TestChainClientGetEndpoints(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"result":{"node_info":{"network":"test-1"}}}`))
+	}))
+	defer srv.Close()
+
+	// the binaries take tcp:// rpc endpoints, and endpoints may have a trailing slash
+	for _, rpc := range []string{srv.URL, srv.URL + "/", "tcp://" + strings.TrimPrefix(srv.URL, "http://")} {
+		network, err := newChainClient(Chain{Name: "test", RPC: rpc}).Network()
+		if err != nil {
+			t.Fatalf("%s: %s", rpc, err)
+		}
+		if network != "test-1" || gotPath != "/status" {
+			t.Fatalf("%s: got network %q from %q", rpc, network, gotPath)
+		}
+	}
+
+	if _, err := newChainClient(Chain{Name: "test"}).Network(); err == nil {
+		t.Fatal("expected an error without an rpc endpoint")
+	}
+	if err := requireREST(Chain{Name: "test", RPC: srv.URL}); err == nil {
+		t.Fatal("expected an error without a rest endpoint")
+	}
+}
+
+func TestChainClientAccSeq(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantAcc int
+		wantSeq int
+		wantErr bool
+	}{
+		{
+			name:    "proto base account",
+			body:    `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"cosmos1abc","pub_key":null,"account_number":"12","sequence":"3"}}`,
+			wantAcc: 12, wantSeq: 3,
+		},
+		{
+			name:    "proto base account with multisig pubkey",
+			body:    `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"cosmos1abc","pub_key":{"@type":"/cosmos.crypto.multisig.LegacyAminoPubKey","threshold":2,"public_keys":[{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"A1"}]},"account_number":"7","sequence":"41"}}`,
+			wantAcc: 7, wantSeq: 41,
+		},
+		{
+			name:    "amino base account",
+			body:    `{"height":"100","result":{},"account":{"type":"cosmos-sdk/BaseAccount","value":{"address":"cosmos1abc","public_key":{"type":"tendermint/PubKeySecp256k1","value":"A1"},"account_number":"5","sequence":"9"}}}`,
+			wantAcc: 5, wantSeq: 9,
+		},
+		{
+			name:    "proto continuous vesting account",
+			body:    `{"account":{"@type":"/cosmos.vesting.v1beta1.ContinuousVestingAccount","base_vesting_account":{"base_account":{"address":"cosmos1abc","account_number":"21","sequence":"4"},"original_vesting":[],"end_time":"1700000000"},"start_time":"1600000000"}}`,
+			wantAcc: 21, wantSeq: 4,
+		},
+		{
+			name:    "amino periodic vesting account",
+			body:    `{"account":{"type":"cosmos-sdk/PeriodicVestingAccount","value":{"base_vesting_account":{"base_account":{"address":"cosmos1abc","account_number":"8","sequence":"1"},"end_time":"1700000000"},"start_time":"1600000000","vesting_periods":[]}}}`,
+			wantAcc: 8, wantSeq: 1,
+		},
+		{
+			name:    "stride periodic vesting account",
+			body:    `{"account":{"@type":"/stride.vesting.StridePeriodicVestingAccount","base_vesting_account":{"base_account":{"address":"stride1abc","account_number":"30","sequence":"6"}},"vesting_periods":[{"start_time":"1600000000","length":"100","action_type":0}]}}`,
+			wantAcc: 30, wantSeq: 6,
+		},
+		{
+			name:    "eth account",
+			body:    `{"account":{"@type":"/ethermint.types.v1.EthAccount","base_account":{"address":"evmos1abc","account_number":"2","sequence":"10"},"code_hash":"0x"}}`,
+			wantAcc: 2, wantSeq: 10,
+		},
+		{
+			name:    "unknown account type",
+			body:    `{"account":{"@type":"/cosmos.auth.v1beta1.ModuleAccount","base_account":{"account_number":"1","sequence":"0"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			body:    `<html>gateway</html>`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(t, http.StatusOK, tc.body)
+			acc, seq, err := newChainClient(Chain{Name: "test", REST: srv.URL}).AccSeq("cosmos1abc")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if acc != tc.wantAcc || seq != tc.wantSeq {
+				t.Fatalf("got account %d sequence %d, want %d and %d", acc, seq, tc.wantAcc, tc.wantSeq)
+			}
+		})
+	}
+}
+
+func TestChainClientAccSeqNotFound(t *testing.T) {
+	srv := newTestServer(t, http.StatusNotFound, `{"code":5,"message":"account cosmos1abc not found"}`)
+	_, _, err := newChainClient(Chain{Name: "test", REST: srv.URL}).AccSeq("cosmos1abc")
+	if !errors.Is(err, errNotFound) {
+		t.Fatalf("got error %v, want errNotFound", err)
+	}
+}
+
+func TestParseBroadcastResult(t *testing.T) {
+	cases := []struct {
+		name     string
+		output   string
+		wantHash string
+		wantCode int
+		wantErr  bool
+	}{
+		{
+			name:     "json",
+			output:   `{"height":"0","txhash":"ABCD","code":0,"raw_log":"[]"}`,
+			wantHash: "ABCD",
+		},
+		{
+			name:     "failed checks",
+			output:   `{"height":"0","txhash":"ABCD","code":32,"raw_log":"account sequence mismatch"}`,
+			wantHash: "ABCD", wantCode: 32,
+		},
+		{
+			name:     "after gas estimate and trailing output",
+			output:   "gas estimate: 123456\n{\"txhash\":\"EF01\",\"code\":0}\nsome trailing line\n",
+			wantHash: "EF01",
+		},
+		{
+			name:    "no json",
+			output:  "Error: post failed: connection refused\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			output:  `{"txhash": `,
+			wantErr: true,
+		},
+		{
+			name:    "no txhash",
+			output:  `{"code":0,"raw_log":""}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseBroadcastResult([]byte(tc.output))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.TxHash != tc.wantHash || result.Code != tc.wantCode {
+				t.Fatalf("got txhash %q code %d, want %q and %d", result.TxHash, result.Code, tc.wantHash, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestChainClientQueryPages(t *testing.T) {
+	var gotKeys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("pagination.key")
+		gotKeys = append(gotKeys, key)
+		switch key {
+		case "":
+			w.Write([]byte(`{"balances":[{"denom":"uatom","amount":"1"}],"pagination":{"next_key":"a+b/=="}}`))
+		case "a+b/==":
+			w.Write([]byte(`{"balances":[{"denom":"uosmo","amount":"2"}],"pagination":{"next_key":null}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	balances, err := newChainClient(Chain{Name: "test", REST: srv.URL}).Balances("cosmos1abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances.Balances) != 2 || balances.Balances[0].Denom != "uatom" || balances.Balances[1].Denom != "uosmo" {
+		t.Fatalf("got balances %+v, want uatom and uosmo", balances.Balances)
+	}
+	if len(gotKeys) != 2 || gotKeys[1] != "a+b/==" {
+		t.Fatalf("got page keys %q", gotKeys)
+	}
+}
+
+func TestChainClientWithoutREST(t *testing.T) {
+	// accounts are queried with the binary through the rpc endpoint
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args.txt")
+	binary := filepath.Join(dir, "testd")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n" +
+		`echo '{"account":{"type":"cosmos-sdk/BaseAccount","value":{"address":"cosmos1abc","account_number":"7","sequence":"41"}}}'` + "\n"
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	client := newChainClient(Chain{Name: "test", Binary: binary, RPC: "http://localhost:26657"})
+	acc, seq, err := client.AccSeq("cosmos1abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc != 7 || seq != 41 {
+		t.Fatalf("got account %d and sequence %d, want 7 and 41", acc, seq)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "query auth account cosmos1abc --node http://localhost:26657 --output json\n"; string(args) != want {
+		t.Fatalf("got args %q, want %q", args, want)
+	}
+	if _, _, err := newChainClient(Chain{Name: "test", Binary: binary}).AccSeq("cosmos1abc"); err == nil {
+		t.Fatal("expected an error without a rest or rpc endpoint")
+	}
+
+	// txs are waited for on the rpc endpoint
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		if r.URL.Query().Get("hash") != "0xABCD" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"error":{"code":-32603,"message":"Internal error","data":"tx (ABCE) not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"result":{"hash":"ABCD","height":"123","tx_result":{"code":11,"log":"out of gas"}}}`))
+	}))
+	defer srv.Close()
+	client = newChainClient(Chain{Name: "test", RPC: srv.URL})
+	res, err := client.WaitTx("ABCD", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TxHash != "ABCD" || res.Height != "123" || res.Code != 11 || res.RawLog != "out of gas" || gotQuery != "hash=0xABCD" {
+		t.Fatalf("got %+v from %q", res, gotQuery)
+	}
+	if _, err := client.Tx("ABCE"); !errors.Is(err, errNotFound) {
+		t.Fatalf("got error %v, want errNotFound", err)
+	}
+}
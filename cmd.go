@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 // VERSION TODO: something more intelligent
@@ -117,8 +118,10 @@ var claimValidatorCmd = &cobra.Command{
 var signCmd = &cobra.Command{
 	Use:   "sign <chain name> <key name>",
 	Short: "sign a tx",
-	Args:  cobra.ExactArgs(2),
-	RunE:  cmdSign,
+	Long: "signs the tx at --index of a chain/key pair with your local key (--from, or the [[signers]] of the config). " +
+		"With --all, walks every pending tx you haven't signed yet, showing a summary of each for confirmation",
+	Args: cobra.MaximumNArgs(2),
+	RunE: cmdSign,
 }
 
 var listCmd = &cobra.Command{
@@ -135,13 +138,288 @@ var broadcastCmd = &cobra.Command{
 	RunE:  cmdBroadcast,
 }
 
+var reindexCmd = &cobra.Command{
+	Use:   "reindex <chain name> <key name>",
+	Short: "renumber the pending txs from 0 and report stale sequences",
+	Long: "moves the pending txs of a chain/key pair to indices 0, 1, 2, ... keeping their order, so the next tx " +
+		"to broadcast is index 0 and `tx push -x` derives the right sequence. This is done automatically after a " +
+		"broadcast. The sequences of the pending txs are then compared with the on-chain one, queried from the rest " +
+		"endpoint or with the binary through the rpc endpoint",
+	Args: cobra.ExactArgs(2),
+	RunE: cmdReindex,
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [chain name] [key name]",
+	Short: "show what needs attention in the pending txs",
+	Long: "groups the pending txs of every chain/key pair (or of one pair) by index, with their description, " +
+		"messages, sequence, who has signed and who hasn't, whether the threshold of the multisig is met, " +
+		"and their age. Use --output json for scripts",
+	Args: cobra.MaximumNArgs(2),
+	RunE: cmdStatus,
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [chain name] [key name]",
+	Short: "compare the sequences of the pending txs with the on-chain sequence",
+	Long: "for a chain/key pair, or every pair with pending txs, lists the pending txs with their sequence, the " +
+		"sequence they will be broadcast with (on-chain sequence + position in the queue) and their signatures. " +
+		"Fails if any tx has the wrong sequence, eg. because another tx used it out-of-band",
+	Args: cobra.MaximumNArgs(2),
+	RunE: cmdCheck,
+}
+
+var resequenceCmd = &cobra.Command{
+	Use:   "resequence <chain name> <key name>",
+	Short: "fix the sequences of the pending txs",
+	Long: "reindexes the pending txs and rewrites the sign data of those with the wrong sequence, re-signing their " +
+		"manifest. Their signatures were made for the old sequence, so they are moved aside to the archive and must be made again",
+	Args: cobra.ExactArgs(2),
+	RunE: cmdResequence,
+}
+
 var deleteCmd = &cobra.Command{
 	Use:   "delete <chain name> <key name>",
-	Short: "delete a tx",
+	Short: "delete a tx, moving it to the archive",
 	Args:  cobra.ExactArgs(2),
 	RunE:  cmdDelete,
 }
 
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "interactively create a new config file",
+	Long: "walks through the user name, keyring backend, storage credentials, keys and chains and writes " +
+		"them to ~/.multisig/config.toml (or the --config path). Use --team to import the keys, chains and " +
+		"bucket from a shared team config, so only the personal settings like localname have to be entered",
+	Args: cobra.NoArgs,
+	RunE: cmdInit,
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "manage the multisig config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configAddChainCmd = &cobra.Command{
+	Use:   "add-chain <registry chain name>",
+	Short: "add a chain to the config using its entry in the chain-registry",
+	Long: "populates the name, binary, prefix, chain id, denom, gas price and rpc, rest and grpc endpoints of a new [[chains]] entry " +
+		"from the chain.json in the chain-registry (https://github.com/cosmos/chain-registry). " +
+		"Only the first public rpc, rest and grpc endpoints are used, the others are written as comments below the entry. " +
+		"Use --registry to read from a local clone of the registry instead of fetching it over http",
+	Args: cobra.ExactArgs(1),
+	RunE: cmdConfigAddChain,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "check the config for mistakes",
+	Long: "checks every [[keys]] and [[chains]] entry, that the binaries are on the PATH and report the chain-id of the chain with `<binary> status`, that the rpc and rest " +
+		"endpoints are reachable and on the expected chain-id (and grpc endpoints reachable), and that the bucket can be written to with a probe write/delete",
+	Args: cobra.NoArgs,
+	RunE: cmdConfigValidate,
+}
+
+var configPullCmd = &cobra.Command{
+	Use:   "pull [file]",
+	Short: "download the shared team config from the bucket",
+	Long:  "writes the team config (the 'teamconfig' object in the bucket, team.toml by default) to the given file, or prints it",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  cmdConfigPull,
+}
+
+var configPushCmd = &cobra.Command{
+	Use:   "push <file>",
+	Short: "upload a file as the shared team config",
+	Long: "the team config holds the [[keys]] and [[chains]] shared by the whole team, and must not hold " +
+		"personal fields like localname. Configs with 'teamconfig' set merge it with their local keys and chains",
+	Args: cobra.ExactArgs(1),
+	RunE: cmdConfigPush,
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff [file]",
+	Short: "show the differences between the shared team config and a local file",
+	Long:  "compares the keys and chains of the team config in the bucket with those of the given file, or of the local config",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  cmdConfigDiff,
+}
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "manage the local copy of the chain-registry",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var registrySyncCmd = &cobra.Command{
+	Use:   "sync [chain names...]",
+	Short: "copy the chain.json and assetlist.json of the configured chains into the local registry",
+	Long: "the local registry (~/.multisig/registry or the 'registry' path in the config) is consulted " +
+		"before the chain-registry when looking up chain information, eg. the fee denom, " +
+		"so machines without internet access can still push txs",
+	RunE: cmdRegistrySync,
+}
+
+var encryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: "manage the keys for client-side encryption of the bucket contents",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var encryptionKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "generate your private key for decrypting the bucket contents",
+	Long: "writes a new private key to the identity file (~/.multisig/identity.key by default) and prints " +
+		"the public key, which has to be added to the [aws.encryption] recipients of everyone's config",
+	Args: cobra.NoArgs,
+	RunE: cmdEncryptionKeygen,
+}
+
+var encryptionShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "print the public key of your identity",
+	Args:  cobra.NoArgs,
+	RunE:  cmdEncryptionShow,
+}
+
+var membersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "manage the members of the team",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var membersKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "generate your member key for signing the manifests of pushed txs",
+	Long: "writes a new ed25519 key to the member key file (~/.multisig/member.key by default) and prints " +
+		"a [[members]] entry to add to everyone's config. Once the config has members, pushed txs get a manifest " +
+		"signed by the pusher, and sign and broadcast refuse txs whose manifest doesn't match or wasn't signed by a member",
+	Args: cobra.NoArgs,
+	RunE: cmdMembersKeygen,
+}
+
+var membersRegisterCmd = &cobra.Command{
+	Use:   "register",
+	Short: "publish the public keys you sign txs with to the bucket",
+	Long: "reads the public keys of your local signers (see [[signers]]) from the keystores " +
+		"of the chains' binaries, or takes them from --pubkey, and uploads them to members/<user>.json. Registered " +
+		"pubkeys let `keys create` take member names, and broadcast check that each <user>.json was signed by that user",
+	Args: cobra.NoArgs,
+	RunE: cmdMembersRegister,
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "manage the multisig keys in the keystores of the binaries",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var keysPortCmd = &cobra.Command{
+	Use:   "port <key name> <from chain name> <to chain name>",
+	Short: "copy a multisig from the keystore of one chain's binary to another's",
+	Long: "reads the threshold and member public keys of the multisig from the keystore of the first chain's binary " +
+		"(or with --query, from its account on the first chain), checks they give the address of the key in the config, " +
+		"and adds the members and the multisig to the keystore of the second chain's binary under the key's localname, " +
+		"so txs can be broadcast on a newly added chain",
+	Args: cobra.ExactArgs(3),
+	RunE: cmdKeysPort,
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create <key name>",
+	Short: "create a new multisig from the public keys of its members",
+	Long: "computes the address of the multisig (a LegacyAminoPubKey) from --threshold and the base64 secp256k1 " +
+		"--members public keys (or names of members who registered a single pubkey), sorted like `keys add --multisig` unless --nosort is given, and adds a [[keys]] entry " +
+		"to the config. With --import, the multisig is also added to the keystore of every chain's binary",
+	Args: cobra.ExactArgs(1),
+	RunE: cmdKeysCreate,
+}
+
+var rewardsCmd = &cobra.Command{
+	Use:   "rewards",
+	Short: "show the unclaimed rewards and commission of every key on every chain",
+	Long: "queries the delegator rewards and validator commission of every key on every chain with a rest endpoint in the config, " +
+		"and shows them in their denom and display unit (from the assetlist.json of the chain-registry). With --push, " +
+		"a `tx withdraw` and/or `tx claim-validator` is pushed for each key whose rewards or commission are above --min, " +
+		"or have any amount of the fee denom of the chain if --min is not given",
+	Args: cobra.NoArgs,
+	RunE: cmdRewards,
+}
+
+var balancesCmd = &cobra.Command{
+	Use:   "balances [chain name] [key name]",
+	Short: "report the balances, delegations, unbonding delegations and vesting schedules of the keys",
+	Long: "queries every key on every chain with a rest endpoint in the config (or the given chain, and key) and reports " +
+		"one row per amount: bank balances, delegations, unbonding delegations with their completion time and " +
+		"the periods of the vesting schedule of vesting accounts with their end time, as a table, CSV or JSON",
+	Args: cobra.MaximumNArgs(2),
+	RunE: cmdBalances,
+}
+
+var govCmd = &cobra.Command{
+	Use:   "gov",
+	Short: "follow the governance proposals of the chains",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var govPendingCmd = &cobra.Command{
+	Use:   "pending [chain name]",
+	Short: "list the proposals in voting period and whether each key voted on them",
+	Long: "queries the proposals in voting period on every chain with a rest endpoint in the config (or the given chain), " +
+		"and shows for each key whether it already voted on-chain, or has a vote tx pending in the bucket. " +
+		"With --draft, a vote tx with that option is pushed for each proposal a key didn't vote on yet, " +
+		"for the team to review before signing",
+	Args: cobra.MaximumNArgs(1),
+	RunE: cmdGovPending,
+}
+
+var grantsCmd = &cobra.Command{
+	Use:   "grants <chain name> <key name>",
+	Short: "list the authz grants and fee allowances given and received by a key",
+	Long: "queries the authz grants and fee allowances the key gave and received on the chain, with their expiration, " +
+		"highlighting those expiring within --days. With --renew, a `tx authz grant` is pushed for each authz grant " +
+		"the key gave that expires within --days, for the given number of days",
+	Args: cobra.ExactArgs(2),
+	RunE: cmdGrants,
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "inspect broadcast and deleted txs",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:   "list [chain name] [key name]",
+	Short: "list the archived txs, optionally of a chain or chain/key pair",
+	Long: "broadcast and deleted txs are moved to archive/<chain>/<key>/<txhash or timestamp>/ in the bucket. " +
+		"Archived txs older than 'archivedays' in the config are removed when a new tx is archived",
+	Args: cobra.MaximumNArgs(2),
+	RunE: cmdArchiveList,
+}
+
+var archiveShowCmd = &cobra.Command{
+	Use:   "show <chain name> <key name> <txhash or timestamp>",
+	Short: "show what was signed, by whom, and what happened to an archived tx",
+	Args:  cobra.ExactArgs(3),
+	RunE:  cmdArchiveShow,
+}
+
 var rawCmd = &cobra.Command{
 	Use:   "raw <cmd>",
 	Short: "raw operations on the s3 bucket",
@@ -196,10 +474,25 @@ var (
 	flagSequence    int
 	flagAccount     int
 	flagNode        string
+	flagREST        string
 	flagFrom        string
 	flagAll         bool
 	flagForce       bool
 	flagAdditional  bool
+	flagUnordered   bool
+	flagTimeout     time.Duration
+	flagOutput      string
+	flagQuery       bool
+	flagThreshold   int
+	flagMembers     []string
+	flagNoSort      bool
+	flagPubKeys     []string
+	flagImport      bool
+	flagPush        bool
+	flagMin         []string
+	flagDraft       string
+	flagDays        int
+	flagRenew       int
 	flagDescription string
 	flagDenom       string
 	flagTxIndex     int
@@ -208,6 +501,11 @@ var (
 	flagFees        string
 	flagMultisigKey string
 	flagHomePath    string
+	flagRegistry    string
+	flagChainName   string
+	flagDryRun      bool
+	flagOffline     bool
+	flagTeamConfig  string
 )
 
 func init() {
@@ -218,6 +516,50 @@ func init() {
 	rootCmd.AddCommand(broadcastCmd)
 	rootCmd.AddCommand(rawCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(reindexCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(resequenceCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(encryptionCmd)
+	rootCmd.AddCommand(membersCmd)
+	rootCmd.AddCommand(rewardsCmd)
+	rootCmd.AddCommand(balancesCmd)
+	rootCmd.AddCommand(govCmd)
+	rootCmd.AddCommand(grantsCmd)
+	rootCmd.AddCommand(registryCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(keysCmd)
+
+	// Config commands
+	configCmd.AddCommand(configAddChainCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPullCmd)
+	configCmd.AddCommand(configPushCmd)
+	configCmd.AddCommand(configDiffCmd)
+
+	// Encryption commands
+	encryptionCmd.AddCommand(encryptionKeygenCmd)
+	encryptionCmd.AddCommand(encryptionShowCmd)
+
+	// Members commands
+	membersCmd.AddCommand(membersKeygenCmd)
+	membersCmd.AddCommand(membersRegisterCmd)
+
+	// Gov commands
+	govCmd.AddCommand(govPendingCmd)
+
+	// Archive commands
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveShowCmd)
+
+	// Keys commands
+	keysCmd.AddCommand(keysPortCmd)
+	keysCmd.AddCommand(keysCreateCmd)
+
+	// Registry commands
+	registryCmd.AddCommand(registrySyncCmd)
 
 	// Raw commands
 	rawCmd.AddCommand(rawBech32Cmd)
@@ -274,5 +616,34 @@ func init() {
 
 	addDeleteCmdFlags(deleteCmd)
 
+	addReindexCmdFlags(reindexCmd)
+	addReindexCmdFlags(checkCmd)
+	addReindexCmdFlags(resequenceCmd)
+
+	addStatusCmdFlags(statusCmd)
+
+	addKeysPortCmdFlags(keysPortCmd)
+	addKeysCreateCmdFlags(keysCreateCmd)
+
+	addInitCmdFlags(initCmd)
+
+	addConfigAddChainCmdFlags(configAddChainCmd)
+
+	addConfigValidateCmdFlags(configValidateCmd)
+
+	addRegistrySyncCmdFlags(registrySyncCmd)
+
+	addKeygenCmdFlags(encryptionKeygenCmd)
+	addRewardsCmdFlags(rewardsCmd)
+	addTxCmdGasFeesFlags(rewardsCmd)
+	addBalancesCmdFlags(balancesCmd)
+	addGovPendingCmdFlags(govPendingCmd)
+	addTxCmdGasFeesFlags(govPendingCmd)
+	addGrantsCmdFlags(grantsCmd)
+	addTxCmdGasFeesFlags(grantsCmd)
+
+	addKeygenCmdFlags(membersKeygenCmd)
+	addMembersRegisterCmdFlags(membersRegisterCmd)
+
 	addGlobalFlags(rootCmd)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 
@@ -14,42 +15,91 @@ import (
 var (
 	defaultBucketRegion = "ca-central-1"
 	defaultGas          = 300000
+	defaultThreshold    = 2
 )
 
 // A chain we sign txs on
 type Chain struct {
-	Name   string // chain name
-	Binary string // binary to use for signing
-	Prefix string // bech32 address prefix
-	ID     string // chain id for signing
-	Node   string // node to broadcast signed txs to
-	Denom  string // native denom
+	Name     string `toml:"name"`               // chain name
+	Binary   string `toml:"binary"`             // binary to use for signing
+	Prefix   string `toml:"prefix"`             // bech32 address prefix
+	ID       string `toml:"id"`                 // chain id for signing
+	Node     string `toml:"node,omitempty"`     // deprecated, same as rpc
+	RPC      string `toml:"rpc,omitempty"`      // tendermint rpc endpoint the binary generates and broadcasts txs with
+	REST     string `toml:"rest,omitempty"`     // rest endpoint to query accounts, balances, node info and txs from
+	GRPC     string `toml:"grpc,omitempty"`     // grpc endpoint, only checked by config validate for now
+	Denom    string `toml:"denom,omitempty"`    // native denom
+	GasPrice string `toml:"gasprice,omitempty"` // gas price suggested with the fees when --fees is not given, eg. 0.025uatom
+
+	KeyringBackend string `toml:"keyringbackend,omitempty"` // keyring backend of the keystore of the binary, defaults to the global one
+	Home           string `toml:"home,omitempty"`           // home of the binary holding the keystore, instead of --home
 }
 
 // A key we sign txs with
 type Key struct {
-	Name      string
-	Address   string
-	LocalName string
+	Name      string `toml:"name"`
+	Address   string `toml:"address,omitempty"` // empty in local entries only giving the localname of a team config key
+	LocalName string `toml:"localname,omitempty"`
+	Threshold int    `toml:"threshold,omitempty"` // signatures needed to broadcast if the multisig can't be read, defaults to 2
 }
 
-// Credentials for AWS
+// number of signatures needed to broadcast a tx of the key when its multisig can't be read
+func (k Key) GetThreshold() int {
+	if k.Threshold > 0 {
+		return k.Threshold
+	}
+	return defaultThreshold
+}
+
+// Credentials for AWS. If none of pub/priv or credentialprocess are set,
+// the standard aws credential chain is used (env vars, profiles, roles)
 type AWS struct {
-	Address      string
-	Bucket       string
-	BucketRegion string
-	Pub          string
-	Priv         string
+	Address           string `toml:"address,omitempty"`
+	Bucket            string `toml:"bucket"`
+	BucketRegion      string `toml:"bucketregion,omitempty"`
+	Pub               string `toml:"pub,omitempty"`
+	Priv              string `toml:"priv,omitempty"`
+	SessionToken      string `toml:"sessiontoken,omitempty"`
+	Profile           string `toml:"profile,omitempty"`           // profile in ~/.aws/credentials and ~/.aws/config
+	CredentialProcess string `toml:"credentialprocess,omitempty"` // command printing credentials, like the credential_process of the aws cli
+
+	Encryption Encryption `toml:"encryption"` // client-side encryption of the objects in the bucket, not omitempty as toml can't compare it
 }
 
 // Config file
 type Config struct {
-	User           string
-	KeyringBackend string
-	DefaultGas     int64
-	AWS            AWS
-	Keys           []Key
-	Chains         []Chain
+	User              string   `toml:"user"`
+	KeyringBackend    string   `toml:"keyringbackend"`
+	DefaultGas        int64    `toml:"defaultGas,omitempty"`
+	Registry          string   `toml:"registry,omitempty"`          // local chain-registry checkout, defaults to ~/.multisig/registry
+	TeamConfig        string   `toml:"teamconfig,omitempty"`        // shared team config in the bucket, eg. team.toml
+	MemberKey         string   `toml:"memberkey,omitempty"`         // your key for signing manifests, defaults to ~/.multisig/member.key
+	ArchiveDays       int      `toml:"archivedays,omitempty"`       // days to keep archived txs for, 0 keeps them forever
+	AllowUnregistered bool     `toml:"allowunregistered,omitempty"` // with members, let unregistered or unverifiable signers through with a warning
+	AWS               AWS      `toml:"aws"`
+	Keys              []Key    `toml:"keys"`
+	Chains            []Chain  `toml:"chains"`
+	Members           []Member `toml:"members,omitempty"`
+	Signers           []Signer `toml:"signers,omitempty"`
+}
+
+// keyring backend of the keystore of a chain, defaults to the global one
+func (c *Config) GetKeyringBackend(chain Chain) string {
+	if chain.KeyringBackend != "" {
+		return chain.KeyringBackend
+	}
+	return c.KeyringBackend
+}
+
+// home of the keystore of a chain: --home, or the home of the chain if set
+func chainHome(chain Chain) (string, error) {
+	if flagHomePath != "" {
+		return flagHomePath, nil
+	}
+	if chain.Home != "" {
+		return expandHome(chain.Home)
+	}
+	return "", nil
 }
 
 func (c *Config) GetChain(name string) (Chain, bool) {
@@ -70,20 +120,47 @@ func (c *Config) GetKey(name string) (Key, bool) {
 	return Key{}, false
 }
 
-// load toml config
+// resolve the path of the config file to use. If no filename is given,
+// use the one in the present working directory, then the global one
+func configFilePath(filename string) (string, error) {
+	if filename != "" {
+		return filename, nil
+	}
+	if _, err := os.Stat(defaultLocalConfigFile); err == nil {
+		return defaultLocalConfigFile, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(usr.HomeDir, defaultGlobalConfigFile), nil
+}
+
+// load toml config, merged with the shared team config if there is one
 func loadConfig(filename string) (*Config, error) {
-	if filename == "" {
-		if _, err := os.Stat(defaultLocalConfigFile); err == nil {
-			filename = defaultLocalConfigFile
-		} else {
-			usr, err := user.Current()
-			if err != nil {
-				return nil, err
-			}
-			filename = path.Join(usr.HomeDir, defaultGlobalConfigFile)
+	filename, err := configFilePath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := loadLocalConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TeamConfig != "" {
+		team, err := fetchTeamConfig(c, filename)
+		if err != nil {
+			return nil, err
 		}
+		mergeTeamConfig(c, team)
 	}
 
+	return c, nil
+}
+
+// load toml config from the given file only
+func loadLocalConfig(filename string) (*Config, error) {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -94,6 +171,7 @@ func loadConfig(filename string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	migrateNodes(c.Chains)
 
 	if c.AWS.BucketRegion == "" {
 		c.AWS.BucketRegion = defaultBucketRegion
@@ -102,6 +180,29 @@ func loadConfig(filename string) (*Config, error) {
 	return c, nil
 }
 
+// use the deprecated node of the chains as their rpc endpoint.
+// It's never used as the rest endpoint, which chains with only a node don't have
+func migrateNodes(chains []Chain) {
+	for i := range chains {
+		if chains[i].RPC == "" {
+			chains[i].RPC = chains[i].Node
+		}
+		chains[i].Node = ""
+	}
+}
+
+// expand a leading ~ in a path to the home directory of the current user
+func expandHome(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(usr.HomeDir, strings.TrimPrefix(p, "~")), nil
+}
+
 // convert the prefix on a bech32 address
 func bech32ify(addrBech, prefix string) (string, error) {
 	hrp, addrBytes, err := bech32.DecodeAndConvert(addrBech)
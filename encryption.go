@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+var (
+	// encrypted objects start with this line, followed by the json Envelope
+	envelopeHeader = []byte("multisig-encrypted-v1\n")
+
+	// default file holding the private key used to decrypt objects
+	defaultIdentityFile = ".multisig/identity.key"
+)
+
+// Encryption of the objects in the bucket. Objects are encrypted with a random
+// data key, which is sealed to the public key of each recipient
+type Encryption struct {
+	Recipients []string `toml:"recipients,omitempty"` // base64 public keys of everyone who should be able to read objects
+	Identity   string   `toml:"identity,omitempty"`   // file with your private key, defaults to ~/.multisig/identity.key
+	// read unencrypted objects even with recipients, while a bucket in use is migrated to encryption
+	AllowPlaintext bool `toml:"allowplaintext,omitempty"`
+}
+
+// Envelope of an encrypted object
+type Envelope struct {
+	Recipients []SealedKey `json:"recipients"`
+	Nonce      string      `json:"nonce"`
+	Data       string      `json:"data"`
+}
+
+// SealedKey is the data key of an Envelope, sealed to a recipient
+type SealedKey struct {
+	Recipient string `json:"recipient"`
+	Key       string `json:"key"`
+}
+
+// encrypt an object for all the recipients, or return it as-is if there are none
+func encryptObject(enc Encryption, plaintext []byte) ([]byte, error) {
+	if len(enc.Recipients) == 0 {
+		return plaintext, nil
+	}
+
+	var dataKey [32]byte
+	if _, err := rand.Read(dataKey[:]); err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	envelope := Envelope{
+		Nonce: base64.StdEncoding.EncodeToString(nonce[:]),
+		Data:  base64.StdEncoding.EncodeToString(secretbox.Seal(nil, plaintext, &nonce, &dataKey)),
+	}
+	for _, recipient := range enc.Recipients {
+		pub, err := decodeKey(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption recipient %s: %s", recipient, err)
+		}
+		sealed, err := box.SealAnonymous(nil, dataKey[:], pub, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Recipients = append(envelope.Recipients, SealedKey{
+			Recipient: recipient,
+			Key:       base64.StdEncoding.EncodeToString(sealed),
+		})
+	}
+
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, envelopeHeader...), envelopeBytes...), nil
+}
+
+// decrypt an object with our identity, or return it as-is if it isn't encrypted
+func decryptObject(enc Encryption, b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, envelopeHeader) {
+		// anyone who can write to the bucket could otherwise replace a tx with an unencrypted one
+		if len(enc.Recipients) > 0 && !enc.AllowPlaintext {
+			return nil, fmt.Errorf("object is not encrypted although encryption recipients are configured, " +
+				"set allowplaintext = true in [aws.encryption] to read it while migrating the bucket")
+		}
+		return b, nil
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(bytes.TrimPrefix(b, envelopeHeader), &envelope); err != nil {
+		return nil, fmt.Errorf("cannot parse encrypted object: %s", err)
+	}
+
+	pub, priv, err := loadIdentity(enc)
+	if err != nil {
+		return nil, fmt.Errorf("object is encrypted but the identity can't be loaded: %s", err)
+	}
+	ourKey := base64.StdEncoding.EncodeToString(pub[:])
+
+	for _, sealedKey := range envelope.Recipients {
+		if sealedKey.Recipient != ourKey {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(sealedKey.Key)
+		if err != nil {
+			return nil, err
+		}
+		dataKeyBytes, ok := box.OpenAnonymous(nil, sealed, pub, priv)
+		if !ok || len(dataKeyBytes) != 32 {
+			return nil, fmt.Errorf("cannot open the data key sealed to %s", ourKey)
+		}
+		var dataKey [32]byte
+		copy(dataKey[:], dataKeyBytes)
+
+		nonceBytes, err := base64.StdEncoding.DecodeString(envelope.Nonce)
+		if err != nil || len(nonceBytes) != 24 {
+			return nil, fmt.Errorf("invalid nonce in encrypted object")
+		}
+		var nonce [24]byte
+		copy(nonce[:], nonceBytes)
+
+		data, err := base64.StdEncoding.DecodeString(envelope.Data)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, ok := secretbox.Open(nil, data, &nonce, &dataKey)
+		if !ok {
+			return nil, fmt.Errorf("cannot decrypt object, it may have been tampered with")
+		}
+		return plaintext, nil
+	}
+
+	return nil, fmt.Errorf("object is not encrypted to our public key %s", ourKey)
+}
+
+func identityFile(enc Encryption) (string, error) {
+	if enc.Identity != "" {
+		return expandHome(enc.Identity)
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, defaultIdentityFile), nil
+}
+
+// load our private key, and derive the public key from it
+func loadIdentity(enc Encryption) (*[32]byte, *[32]byte, error) {
+	filename, err := identityFile(enc)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, err := decodeKey(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid identity in %s: %s", filename, err)
+	}
+
+	var pub [32]byte
+	curve25519.ScalarBaseMult(&pub, priv)
+	return &pub, priv, nil
+}
+
+func decodeKey(s string) (*[32]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(b))
+	}
+	var key [32]byte
+	copy(key[:], b)
+	return &key, nil
+}
+
+// generate a new identity and print its public key, to be added to the recipients of the team
+func cmdEncryptionKeygen(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	filename, err := identityFile(conf.AWS.Encryption)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filename); err == nil && !flagForce {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, []byte(base64.StdEncoding.EncodeToString(priv[:])+"\n"), 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote your private key to %s\n", filename)
+	fmt.Println("add your public key to the recipients of everyone's config:")
+	fmt.Println(base64.StdEncoding.EncodeToString(pub[:]))
+	return nil
+}
+
+// print the public key of our identity
+func cmdEncryptionShow(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	pub, _, err := loadIdentity(conf.AWS.Encryption)
+	if err != nil {
+		return err
+	}
+	fmt.Println(base64.StdEncoding.EncodeToString(pub[:]))
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// a new identity written to a temporary file, as an Encryption reading it and its base64 public key
+func newTestIdentity(t *testing.T) (Encryption, string) {
+	t.Helper()
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "identity.key")
+	if err := os.WriteFile(filename, []byte(base64.StdEncoding.EncodeToString(priv[:])+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Encryption{Identity: filename}, base64.StdEncoding.EncodeToString(pub[:])
+}
+
+// change the envelope of an encrypted object
+func tamperEnvelope(t *testing.T, b []byte, tamper func(e *Envelope)) []byte {
+	t.Helper()
+	var envelope Envelope
+	if err := json.Unmarshal(bytes.TrimPrefix(b, envelopeHeader), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	tamper(&envelope)
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, envelopeHeader...), envelopeBytes...)
+}
+
+// flip a bit of base64 data
+func flipBit(t *testing.T, s string) string {
+	t.Helper()
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1] ^= 1
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestEncryptDecryptObject(t *testing.T) {
+	alice, alicePub := newTestIdentity(t)
+	bob, bobPub := newTestIdentity(t)
+	eve, _ := newTestIdentity(t)
+	plaintext := []byte(`{"body":{"messages":[]}}`)
+
+	cases := []struct {
+		name       string
+		recipients []string
+		decryptAs  Encryption
+		tamper     func(t *testing.T, b []byte) []byte
+		wantErr    string
+	}{
+		{name: "single recipient", recipients: []string{alicePub}, decryptAs: alice},
+		{name: "second of several recipients", recipients: []string{alicePub, bobPub}, decryptAs: bob},
+		{name: "not a recipient", recipients: []string{alicePub, bobPub}, decryptAs: eve, wantErr: "not encrypted to our public key"},
+		{name: "no identity", recipients: []string{alicePub}, decryptAs: Encryption{Identity: filepath.Join(t.TempDir(), "missing.key")}, wantErr: "identity can't be loaded"},
+		{
+			name: "tampered data", recipients: []string{alicePub}, decryptAs: alice, wantErr: "tampered with",
+			tamper: func(t *testing.T, b []byte) []byte {
+				return tamperEnvelope(t, b, func(e *Envelope) { e.Data = flipBit(t, e.Data) })
+			},
+		},
+		{
+			name: "tampered nonce", recipients: []string{alicePub}, decryptAs: alice, wantErr: "tampered with",
+			tamper: func(t *testing.T, b []byte) []byte {
+				return tamperEnvelope(t, b, func(e *Envelope) { e.Nonce = flipBit(t, e.Nonce) })
+			},
+		},
+		{
+			name: "tampered sealed key", recipients: []string{alicePub}, decryptAs: alice, wantErr: "cannot open the data key",
+			tamper: func(t *testing.T, b []byte) []byte {
+				return tamperEnvelope(t, b, func(e *Envelope) { e.Recipients[0].Key = flipBit(t, e.Recipients[0].Key) })
+			},
+		},
+		{
+			name: "truncated envelope", recipients: []string{alicePub}, decryptAs: alice, wantErr: "cannot parse encrypted object",
+			tamper: func(t *testing.T, b []byte) []byte {
+				return b[:len(b)-10]
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, err := encryptObject(Encryption{Recipients: tc.recipients}, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(encrypted, envelopeHeader) || bytes.Contains(encrypted, plaintext) {
+				t.Fatalf("object is not encrypted: %s", encrypted)
+			}
+			if tc.tamper != nil {
+				encrypted = tc.tamper(t, encrypted)
+			}
+
+			decrypted, err := decryptObject(tc.decryptAs, encrypted)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Fatalf("got %q, want %q", decrypted, plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptObjectInvalidRecipient(t *testing.T) {
+	if _, err := encryptObject(Encryption{Recipients: []string{"not base64!"}}, []byte("x")); err == nil {
+		t.Fatal("expected an error for an invalid recipient")
+	}
+	if _, err := encryptObject(Encryption{Recipients: []string{base64.StdEncoding.EncodeToString([]byte("short"))}}, []byte("x")); err == nil {
+		t.Fatal("expected an error for a recipient of the wrong size")
+	}
+}
+
+func TestPlaintextPassthrough(t *testing.T) {
+	alice, alicePub := newTestIdentity(t)
+	plaintext := []byte(`{"body":{"messages":[]}}`)
+
+	// without recipients objects are written as-is, and objects without the header are read as-is,
+	// whether or not there is an identity to decrypt with
+	b, err := encryptObject(Encryption{}, plaintext)
+	if err != nil || !bytes.Equal(b, plaintext) {
+		t.Fatalf("got %q and %v, want the plaintext", b, err)
+	}
+	for _, enc := range []Encryption{alice, {Identity: filepath.Join(t.TempDir(), "missing.key")}} {
+		b, err := decryptObject(enc, plaintext)
+		if err != nil || !bytes.Equal(b, plaintext) {
+			t.Fatalf("got %q and %v, want the plaintext", b, err)
+		}
+	}
+
+	// with recipients, unencrypted objects are refused unless migrating
+	alice.Recipients = []string{alicePub}
+	if _, err := decryptObject(alice, plaintext); err == nil || !strings.Contains(err.Error(), "allowplaintext") {
+		t.Fatalf("got error %v, want unencrypted objects refused", err)
+	}
+	alice.AllowPlaintext = true
+	if b, err := decryptObject(alice, plaintext); err != nil || !bytes.Equal(b, plaintext) {
+		t.Fatalf("got %q and %v, want the plaintext while migrating", b, err)
+	}
+}
+
+// the etag of an object, its quoted md5 like s3
+func testETag(b []byte) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", md5.Sum(b)))
+}
+
+// serve a bucket from memory, like s3 with path-style addressing
+func newTestBucket(t *testing.T) (AWS, map[string][]byte) {
+	t.Helper()
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			prefix := r.URL.Query().Get("prefix")
+			names := []string{}
+			for name := range objects {
+				if strings.HasPrefix(name, prefix) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>`)
+			for _, name := range names {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", name, len(objects[name]))
+			}
+			fmt.Fprint(w, `</ListBucketResult>`)
+		case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+			src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+			objects[key] = objects[strings.TrimPrefix(src, "bucket/")]
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><CopyObjectResult><ETag>"x"</ETag></CopyObjectResult>`)
+		case r.Method == http.MethodPut:
+			// conditional puts, as used by the locks
+			existing, found := objects[key]
+			ifMatch := r.Header.Get("If-Match")
+			if (r.Header.Get("If-None-Match") == "*" && found) || (ifMatch != "" && (!found || ifMatch != testETag(existing))) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+				return
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			objects[key] = b
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet:
+			b, found := objects[key]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+				return
+			}
+			w.Header().Set("ETag", testETag(b))
+			w.Write(b)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return AWS{Address: srv.URL, Bucket: "bucket", BucketRegion: defaultBucketRegion, Pub: "test", Priv: "test"}, objects
+}
+
+func TestAwsDownloadBytes(t *testing.T) {
+	alice, alicePub := newTestIdentity(t)
+	conf, objects := newTestBucket(t)
+	conf.Encryption = alice
+	sess := awsSession(conf)
+	plaintext := []byte(`{"body":{"messages":[]}}`)
+
+	// objects pushed before encryption was enabled are still readable
+	objects["cosmoshub/validator/0/unsigned.json"] = plaintext
+	b, err := awsDownloadBytes(sess, conf, "cosmoshub/validator/0", "unsigned.json")
+	if err != nil || !bytes.Equal(b, plaintext) {
+		t.Fatalf("got %q and %v, want the plaintext", b, err)
+	}
+
+	// objects are encrypted in the bucket once there are recipients
+	conf.Encryption.Recipients = []string{alicePub}
+	if err := awsUpload(sess, conf, "cosmoshub/validator/1", "unsigned.json", plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if stored := objects["cosmoshub/validator/1/unsigned.json"]; !bytes.HasPrefix(stored, envelopeHeader) {
+		t.Fatalf("object is stored unencrypted: %q", stored)
+	}
+	b, err = awsDownloadBytes(sess, conf, "cosmoshub/validator/1", "unsigned.json")
+	if err != nil || !bytes.Equal(b, plaintext) {
+		t.Fatalf("got %q and %v, want the plaintext", b, err)
+	}
+
+	// unencrypted objects can't be slipped in once there are recipients
+	if _, err := awsDownloadBytes(sess, conf, "cosmoshub/validator/0", "unsigned.json"); err == nil {
+		t.Fatal("expected an unencrypted object to be refused")
+	}
+
+	if _, err := awsDownloadBytes(sess, conf, "cosmoshub/validator/2", "unsigned.json"); !isNoSuchKey(err) {
+		t.Fatalf("got error %v, want NoSuchKey", err)
+	}
+}
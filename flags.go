@@ -6,10 +6,13 @@ import "github.com/spf13/cobra"
 func addTxCmdCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().IntVarP(&flagSequence, "sequence", "s", 0, "sequence number for the tx")
 	cmd.Flags().IntVarP(&flagAccount, "account", "a", 0, "account number for the tx")
-	cmd.Flags().StringVarP(&flagNode, "node", "n", "", "tendermint rpc node to get sequence and account number from")
+	cmd.Flags().StringVarP(&flagNode, "node", "n", "", "tendermint rpc endpoint to generate the tx with. flag overrides config")
+	cmd.Flags().StringVarP(&flagREST, "rest", "", "", "rest endpoint to get the account and sequence numbers from. flag overrides config")
 	cmd.Flags().BoolVarP(&flagForce, "force", "f", false, "overwrite files already there")
 	cmd.Flags().BoolVarP(&flagAdditional, "additional", "x", false, "add additional txs with higher sequence number")
 	cmd.Flags().StringVarP(&flagDescription, "description", "i", "", "information about the transaction")
+	cmd.Flags().BoolVarP(&flagUnordered, "unordered", "", false, "push an unordered tx, which can be broadcast in any order (cosmos-sdk v0.53+)")
+	cmd.Flags().DurationVarP(&flagTimeout, "timeout", "", 0, "how long an unordered tx can be signed and broadcast for, required with --unordered and at most the chain's max timeout, e.g. 10m")
 }
 
 // addTxCmdGasFeesFlags defines flags for gas and fees to be used in transactions
@@ -26,8 +29,8 @@ func addDenomFlags(cmd *cobra.Command) {
 // addSignCmdFlags defines common flags to be used in the sign command
 func addSignCmdFlags(cmd *cobra.Command) {
 	cmd.Flags().IntVarP(&flagTxIndex, "index", "i", 0, "index of the tx to sign")
-	cmd.Flags().StringVarP(&flagFrom, "from", "f", "", "name of your local key to sign with")
-	cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVarP(&flagFrom, "from", "f", "", "name of your local key to sign with, flag overrides the [[signers]] of the config")
+	cmd.Flags().BoolVarP(&flagAll, "all", "a", false, "sign every pending tx you haven't signed yet, asking for confirmation")
 }
 
 // addListCmdFlags defines common flags to be used in the list command
@@ -37,7 +40,8 @@ func addListCmdFlags(cmd *cobra.Command) {
 
 // addBroadcastCmdFlags defines common flags to be used in the broadcast command
 func addBroadcastCmdFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&flagNode, "node", "n", "", "node address to broadcast too. flag overrides config")
+	cmd.Flags().StringVarP(&flagNode, "node", "n", "", "tendermint rpc endpoint to broadcast to. flag overrides config")
+	cmd.Flags().StringVarP(&flagREST, "rest", "", "", "rest endpoint to check the tx was executed with. flag overrides config")
 	cmd.Flags().IntVarP(&flagTxIndex, "index", "i", 0, "index of the tx to broadcast")
 	cmd.Flags().StringVarP(&flagMultisigKey, "key", "k", "", "name of the local multisig key name, flag overrides the config")
 }
@@ -47,6 +51,93 @@ func addDeleteCmdFlags(cmd *cobra.Command) {
 	cmd.Flags().IntVarP(&flagTxIndex, "index", "i", 0, "index of the tx to delete")
 }
 
+// addReindexCmdFlags defines flags to be used in the reindex, check and resequence commands
+func addReindexCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagREST, "rest", "", "", "rest endpoint to get the on-chain sequence from. flag overrides config")
+}
+
+// addStatusCmdFlags defines flags to be used in the status command
+func addStatusCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "table", "output format, table or json")
+}
+
+// addRewardsCmdFlags defines flags to be used in the rewards command
+func addRewardsCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "table", "output format, table or json")
+	cmd.Flags().BoolVarP(&flagPush, "push", "p", false, "push txs to claim the rewards and commission above --min")
+	cmd.Flags().StringSliceVarP(&flagMin, "min", "m", nil, "comma separated minimum amounts to claim, in denoms or display units, e.g. 10atom,5000000uosmo")
+}
+
+// addBalancesCmdFlags defines flags to be used in the balances command
+func addBalancesCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "table", "output format, table, csv or json")
+}
+
+// addGovPendingCmdFlags defines flags to be used in the gov pending command
+func addGovPendingCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "table", "output format, table or json")
+	cmd.Flags().StringVarP(&flagDraft, "draft", "d", "", "push a vote tx with this option (yes, no, abstain, no_with_veto) for each proposal not voted on yet")
+}
+
+// addGrantsCmdFlags defines flags to be used in the grants command
+func addGrantsCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "table", "output format, table or json")
+	cmd.Flags().StringVarP(&flagREST, "rest", "", "", "rest endpoint to query the grants from. flag overrides config")
+	cmd.Flags().IntVarP(&flagDays, "days", "d", 30, "highlight the grants expiring within this many days")
+	cmd.Flags().IntVarP(&flagRenew, "renew", "r", 0, "push txs renewing the authz grants given that expire within --days, for this many days")
+}
+
+// addKeysPortCmdFlags defines flags to be used in the keys port command
+func addKeysPortCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&flagQuery, "query", "q", false, "read the multisig from its account on the first chain instead of the keystore")
+	cmd.Flags().StringVarP(&flagREST, "rest", "", "", "rest endpoint of the first chain to query the multisig from. flag overrides config")
+}
+
+// addKeysCreateCmdFlags defines flags to be used in the keys create command
+func addKeysCreateCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&flagThreshold, "threshold", "t", 0, "number of signatures needed by the multisig")
+	cmd.Flags().StringSliceVarP(&flagMembers, "members", "m", nil, "comma separated base64 secp256k1 public keys or registered names of the members")
+	cmd.Flags().BoolVarP(&flagNoSort, "nosort", "", false, "keep the public keys in the given order instead of sorting them")
+	cmd.Flags().BoolVarP(&flagImport, "import", "", false, "also add the multisig to the keystore of every chain's binary")
+	cmd.Flags().BoolVarP(&flagDryRun, "dry-run", "", false, "print the [[keys]] entry instead of adding it to the config")
+	cmd.MarkFlagRequired("threshold")
+	cmd.MarkFlagRequired("members")
+}
+
+// addInitCmdFlags defines flags to be used in the init command
+func addInitCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagTeamConfig, "team", "t", "", "copy of the shared team config to take the bucket and keys from, setting teamconfig instead of copying its keys and chains")
+	cmd.Flags().BoolVarP(&flagForce, "force", "f", false, "overwrite an existing config file")
+}
+
+// addConfigAddChainCmdFlags defines flags to be used in the config add-chain command
+func addConfigAddChainCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagRegistry, "registry", "r", "", "path to a local clone of the chain-registry, or its base url")
+	cmd.Flags().StringVarP(&flagChainName, "name", "", "", "name of the chain in the config, defaults to the registry name")
+	cmd.Flags().BoolVarP(&flagDryRun, "dry-run", "", false, "print the chain entry without writing it to the config")
+}
+
+// addConfigValidateCmdFlags defines flags to be used in the config validate command
+func addConfigValidateCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&flagOffline, "offline", "", false, "skip the endpoint and bucket checks")
+}
+
+// addRegistrySyncCmdFlags defines flags to be used in the registry sync command
+func addRegistrySyncCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagRegistry, "registry", "r", "", "path to a local clone of the chain-registry, or its base url, to sync from")
+}
+
+// addKeygenCmdFlags defines flags to be used in the keygen commands
+func addKeygenCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&flagForce, "force", "f", false, "overwrite an existing key file")
+}
+
+// addMembersRegisterCmdFlags defines flags to be used in the members register command
+func addMembersRegisterCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&flagPubKeys, "pubkey", "p", nil, "comma separated base64 secp256k1 public keys to register instead of reading them from the keystores")
+	cmd.Flags().StringVarP(&flagFrom, "from", "f", "", "name of the local signing key to read from the keystores. flag overrides config")
+}
+
 // addGlobalFlags defines flags to be used regardless of the command used
 func addGlobalFlags(cmd *cobra.Command) {
 	rootCmd.PersistentFlags().StringVarP(&flagConfigPath, "config", "c", "", "custom config path")
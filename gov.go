@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// Proposal in voting period on a chain
+type Proposal struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	VotingEndTime string `json:"voting_end_time"`
+}
+
+// ProposalVote is whether a key voted on a proposal, on-chain or with a tx pending in the bucket
+type ProposalVote struct {
+	Chain    string `json:"chain"`
+	Key      string `json:"key"`
+	Proposal string `json:"proposal"`
+	Title    string `json:"title"`
+	Ends     string `json:"ends"`
+	Voted    string `json:"voted,omitempty"`  // the option voted on-chain
+	Queued   []int  `json:"queued,omitempty"` // the indices of the pending vote txs
+}
+
+// response of the proposals query, with the fields of both gov v1beta1 and v1
+type proposalsResponse struct {
+	Proposals []struct {
+		ProposalID string `json:"proposal_id"` // v1beta1
+		ID         string `json:"id"`          // v1
+		Title      string `json:"title"`       // v1, since cosmos-sdk v0.47
+		Content    struct {
+			Title string `json:"title"`
+		} `json:"content"` // v1beta1
+		Messages []struct {
+			Content struct {
+				Title string `json:"title"`
+			} `json:"content"`
+		} `json:"messages"` // v1 legacy proposals
+		VotingEndTime string `json:"voting_end_time"`
+	} `json:"proposals"`
+}
+
+// query the proposals in voting period on a chain, with gov v1 since cosmos-sdk v0.46 and v1beta1 before.
+// Returns the version of gov the chain serves, to query the votes with
+func queryVotingProposals(client *ChainClient) ([]Proposal, string, error) {
+	var resp proposalsResponse
+	govVersion := "v1"
+	err := client.query("/cosmos/gov/v1/proposals?proposal_status=PROPOSAL_STATUS_VOTING_PERIOD", &resp)
+	if errors.Is(err, errNotFound) {
+		govVersion = "v1beta1"
+		err = client.query("/cosmos/gov/v1beta1/proposals?proposal_status=PROPOSAL_STATUS_VOTING_PERIOD", &resp)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	proposals := []Proposal{}
+	for _, p := range resp.Proposals {
+		proposal := Proposal{ID: p.ID, Title: p.Title, VotingEndTime: p.VotingEndTime}
+		if proposal.ID == "" {
+			proposal.ID = p.ProposalID
+		}
+		if proposal.Title == "" {
+			proposal.Title = p.Content.Title
+		}
+		if proposal.Title == "" && len(p.Messages) > 0 {
+			proposal.Title = p.Messages[0].Content.Title
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, govVersion, nil
+}
+
+// query the option an address voted on a proposal, or empty if it didn't vote
+func queryVote(client *ChainClient, govVersion, proposalID, address string) (string, error) {
+	// the options of weighted votes are in options, option is deprecated
+	var vote struct {
+		Vote struct {
+			Option  string `json:"option"`
+			Options []struct {
+				Option string `json:"option"`
+			} `json:"options"`
+		} `json:"vote"`
+	}
+	err := client.query("/cosmos/gov/"+govVersion+"/proposals/"+proposalID+"/votes/"+address, &vote)
+	if errors.Is(err, errNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	voted := []string{}
+	for _, o := range vote.Vote.Options {
+		voted = append(voted, strings.TrimPrefix(o.Option, "VOTE_OPTION_"))
+	}
+	if len(voted) == 0 && vote.Vote.Option != "" && vote.Vote.Option != "VOTE_OPTION_UNSPECIFIED" {
+		voted = append(voted, strings.TrimPrefix(vote.Vote.Option, "VOTE_OPTION_"))
+	}
+	if len(voted) == 0 {
+		voted = append(voted, "?")
+	}
+	return strings.ToLower(strings.Join(voted, ",")), nil
+}
+
+// the proposals voted on by the pending txs of a chain/key pair, with the indices of those txs
+func queuedVotes(sess *session.Session, conf *Config, chainName, keyName string) (map[string][]int, error) {
+	queue, err := listQueue(sess, conf, chainName, keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := map[string][]int{}
+	for _, tx := range queue {
+		unsignedBytes, err := awsDownloadBytes(sess, conf.AWS, filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index)), unsignedJSON)
+		if err != nil {
+			return nil, err
+		}
+		var unsigned struct {
+			Body struct {
+				Messages []struct {
+					Type       string `json:"@type"`
+					ProposalID string `json:"proposal_id"`
+				} `json:"messages"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal(unsignedBytes, &unsigned); err != nil {
+			continue
+		}
+		for _, msg := range unsigned.Body.Messages {
+			if strings.HasSuffix(msg.Type, ".MsgVote") || strings.HasSuffix(msg.Type, ".MsgVoteWeighted") {
+				votes[msg.ProposalID] = append(votes[msg.ProposalID], tx.Index)
+			}
+		}
+	}
+	return votes, nil
+}
+
+// how long until a proposal's voting period ends, eg. 3d4h, or the raw time if it can't be parsed
+func formatVotingEnd(votingEndTime string) string {
+	end, err := time.Parse(time.RFC3339Nano, votingEndTime)
+	if err != nil {
+		return votingEndTime
+	}
+	if time.Until(end) <= 0 {
+		return "ended"
+	}
+	return formatAge(time.Until(end))
+}
+
+// list the proposals in voting period on every chain, whether each key voted on them,
+// and optionally push a draft vote for those it didn't
+func cmdGovPending(cmd *cobra.Command, args []string) error {
+	if flagOutput != "table" && flagOutput != "json" {
+		return fmt.Errorf("invalid --output %q, must be table or json", flagOutput)
+	}
+	if flagDraft != "" {
+		switch flagDraft {
+		case "yes", "no", "abstain", "no_with_veto":
+		default:
+			return fmt.Errorf("invalid --draft %q, must be yes, no, abstain or no_with_veto", flagDraft)
+		}
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	chains := conf.Chains
+	if len(args) > 0 {
+		chain, found := conf.GetChain(args[0])
+		if !found {
+			return fmt.Errorf("chain %s not found in config", args[0])
+		}
+		chains = []Chain{chain}
+	}
+
+	votes := []ProposalVote{}
+	for _, chain := range chains {
+		if err := requireREST(chain); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s: %s\n", chain.Name, err)
+			continue
+		}
+		client := newChainClient(chain)
+		proposals, govVersion, err := queryVotingProposals(client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: cannot query the proposals of %s: %s\n", chain.Name, err)
+			continue
+		}
+		if len(proposals) == 0 {
+			continue
+		}
+
+		for _, key := range conf.Keys {
+			address, err := bech32ify(key.Address, chain.Prefix)
+			if err != nil {
+				return err
+			}
+			queued, err := queuedVotes(sess, conf, chain.Name, key.Name)
+			if err != nil {
+				return err
+			}
+			for _, proposal := range proposals {
+				voted, err := queryVote(client, govVersion, proposal.ID, address)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: cannot query the vote of %s on %s proposal %s: %s\n", key.Name, chain.Name, proposal.ID, err)
+					voted = "?"
+				}
+				votes = append(votes, ProposalVote{
+					Chain:    chain.Name,
+					Key:      key.Name,
+					Proposal: proposal.ID,
+					Title:    proposal.Title,
+					Ends:     proposal.VotingEndTime,
+					Voted:    voted,
+					Queued:   queued[proposal.ID],
+				})
+			}
+		}
+	}
+
+	if flagOutput == "json" {
+		b, err := json.MarshalIndent(votes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if len(votes) == 0 {
+		fmt.Println("no proposals in voting period")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHAIN/KEY\tPROPOSAL\tTITLE\tENDS IN\tVOTED\tQUEUED")
+		for _, vote := range votes {
+			title := vote.Title
+			if len(title) > 50 {
+				title = title[:47] + "..."
+			}
+			queued := []string{}
+			for _, index := range vote.Queued {
+				queued = append(queued, fmt.Sprintf("%d", index))
+			}
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\t%s\t%s\n", vote.Chain, vote.Key, vote.Proposal, orDash(title),
+				formatVotingEnd(vote.Ends), orDash(vote.Voted), joinOrDash(queued))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if flagDraft == "" {
+		return nil
+	}
+	drafts := []ProposalVote{}
+	chainNames := []string{}
+	for _, vote := range votes {
+		if vote.Voted != "" || len(vote.Queued) > 0 {
+			continue
+		}
+		drafts = append(drafts, vote)
+		chainNames = append(chainNames, vote.Chain)
+	}
+	if err := checkPushFees(cmd, conf, chainNames); err != nil {
+		return err
+	}
+	for _, vote := range drafts {
+		vote := vote
+		description := fmt.Sprintf("draft vote %s on proposal %s: %s", flagDraft, vote.Proposal, vote.Title)
+		if err := pushQueued(vote.Chain, vote.Key, description, func(opts pushOptions) error {
+			return voteTx(cmd, []string{vote.Chain, vote.Key, vote.Proposal, flagDraft}, opts)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// serve a body per path and query, and a 404 for everything else
+func newTestRoutes(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, found := routes[r.URL.RequestURI()]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":5,"message":"not found"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestQueryVotingProposals(t *testing.T) {
+	votingPeriod := "?proposal_status=PROPOSAL_STATUS_VOTING_PERIOD"
+	cases := []struct {
+		name        string
+		routes      map[string]string
+		wantVersion string
+		want        []Proposal
+	}{
+		{
+			name: "gov v1",
+			routes: map[string]string{
+				"/cosmos/gov/v1/proposals" + votingPeriod: `{"proposals":[` +
+					`{"id":"12","title":"Upgrade","voting_end_time":"2024-06-01T00:00:00Z"},` +
+					`{"id":"13","messages":[{"@type":"/cosmos.gov.v1.MsgExecLegacyContent","content":{"title":"Legacy"}}],"voting_end_time":"2024-06-02T00:00:00Z"}]}`,
+			},
+			wantVersion: "v1",
+			want: []Proposal{
+				{ID: "12", Title: "Upgrade", VotingEndTime: "2024-06-01T00:00:00Z"},
+				{ID: "13", Title: "Legacy", VotingEndTime: "2024-06-02T00:00:00Z"},
+			},
+		},
+		{
+			name: "gov v1beta1 before cosmos-sdk v0.46",
+			routes: map[string]string{
+				"/cosmos/gov/v1beta1/proposals" + votingPeriod: `{"proposals":[{"proposal_id":"7","content":{"title":"Spend"},"voting_end_time":"2024-06-03T00:00:00Z"}]}`,
+			},
+			wantVersion: "v1beta1",
+			want:        []Proposal{{ID: "7", Title: "Spend", VotingEndTime: "2024-06-03T00:00:00Z"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestRoutes(t, tc.routes)
+			proposals, version, err := queryVotingProposals(newChainClient(Chain{Name: "test", REST: srv.URL}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tc.wantVersion || !reflect.DeepEqual(proposals, tc.want) {
+				t.Fatalf("got %+v with gov %s, want %+v with gov %s", proposals, version, tc.want, tc.wantVersion)
+			}
+		})
+	}
+}
+
+func TestQueryVote(t *testing.T) {
+	srv := newTestRoutes(t, map[string]string{
+		"/cosmos/gov/v1/proposals/1/votes/cosmos1abc":      `{"vote":{"options":[{"option":"VOTE_OPTION_YES","weight":"1.0"}]}}`,
+		"/cosmos/gov/v1/proposals/2/votes/cosmos1abc":      `{"vote":{"options":[{"option":"VOTE_OPTION_YES","weight":"0.7"},{"option":"VOTE_OPTION_ABSTAIN","weight":"0.3"}]}}`,
+		"/cosmos/gov/v1beta1/proposals/3/votes/cosmos1abc": `{"vote":{"option":"VOTE_OPTION_NO_WITH_VETO","options":[]}}`,
+	})
+	client := newChainClient(Chain{Name: "test", REST: srv.URL})
+	cases := []struct {
+		version, proposal, want string
+	}{
+		{"v1", "1", "yes"},
+		{"v1", "2", "yes,abstain"},
+		{"v1beta1", "3", "no_with_veto"},
+		{"v1", "4", ""}, // not voted
+	}
+	for _, tc := range cases {
+		got, err := queryVote(client, tc.version, tc.proposal, "cosmos1abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Fatalf("proposal %s: got vote %q, want %q", tc.proposal, got, tc.want)
+		}
+	}
+}
+
+func TestQueuedVotes(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	conf := &Config{AWS: bucket}
+	objects["cosmoshub/validator/0/unsigned.json"] = []byte(`{"body":{"messages":[{"@type":"/cosmos.gov.v1beta1.MsgVote","proposal_id":"12","option":"VOTE_OPTION_YES"}]}}`)
+	objects["cosmoshub/validator/0/signdata.json"] = []byte(`{}`)
+	objects["cosmoshub/validator/1/unsigned.json"] = []byte(`{"body":{"messages":[{"@type":"/cosmos.bank.v1beta1.MsgSend"}]}}`)
+	objects["cosmoshub/validator/1/signdata.json"] = []byte(`{}`)
+	objects["cosmoshub/validator/2/unsigned.json"] = []byte(`{"body":{"messages":[{"@type":"/cosmos.gov.v1.MsgVoteWeighted","proposal_id":"12"},{"@type":"/cosmos.gov.v1.MsgVote","proposal_id":"13"}]}}`)
+	objects["cosmoshub/validator/2/signdata.json"] = []byte(`{}`)
+
+	votes, err := queuedVotes(awsSession(bucket), conf, "cosmoshub", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]int{"12": {0, 2}, "13": {2}}; !reflect.DeepEqual(votes, want) {
+		t.Fatalf("got %v, want %v", votes, want)
+	}
+}
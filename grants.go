@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// GrantEntry is an authz grant or fee allowance given or received by a key
+type GrantEntry struct {
+	Kind          string `json:"kind"`      // authz or feegrant
+	Direction     string `json:"direction"` // given or received
+	Granter       string `json:"granter"`
+	Grantee       string `json:"grantee"`
+	Authorization string `json:"authorization"` // the granted msg type, or the type of authorization or allowance
+	Expiration    string `json:"expiration,omitempty"`
+	Expiring      bool   `json:"expiring"`
+}
+
+// response of the authz grants by granter and grantee queries
+type authzGrantsResponse struct {
+	Grants []struct {
+		Granter       string                 `json:"granter"`
+		Grantee       string                 `json:"grantee"`
+		Authorization map[string]interface{} `json:"authorization"`
+		Expiration    string                 `json:"expiration"`
+	} `json:"grants"`
+}
+
+// response of the fee allowances issued by a granter and received by a grantee queries
+type feegrantsResponse struct {
+	Allowances []struct {
+		Granter   string                 `json:"granter"`
+		Grantee   string                 `json:"grantee"`
+		Allowance map[string]interface{} `json:"allowance"`
+	} `json:"allowances"`
+}
+
+// run a grants query, skipping it with a warning if the node doesn't serve it,
+// eg. grants by granter before cosmos-sdk v0.46
+func queryGrants(client *ChainClient, path string, v interface{}) (bool, error) {
+	err := client.query(path, v)
+	if errors.Is(err, errNotFound) {
+		fmt.Fprintf(os.Stderr, "WARNING: %s doesn't serve %s, skipping\n", client.chain.REST, path)
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// the expiration of an allowance, which is nested in the basic allowance of periodic
+// and allowed msg allowances
+func allowanceExpiration(allowance map[string]interface{}) string {
+	if expiration, ok := allowance["expiration"].(string); ok {
+		return expiration
+	}
+	for _, field := range []string{"basic", "allowance"} {
+		if inner, ok := allowance[field].(map[string]interface{}); ok {
+			if expiration := allowanceExpiration(inner); expiration != "" {
+				return expiration
+			}
+		}
+	}
+	return ""
+}
+
+// the authz grants and fee allowances given and received by an address
+func queryKeyGrants(client *ChainClient, address string) ([]GrantEntry, error) {
+	entries := []GrantEntry{}
+
+	for _, direction := range []string{"given", "received"} {
+		authzPath := "/cosmos/authz/v1beta1/grants/granter/" + address
+		feegrantPath := "/cosmos/feegrant/v1beta1/issued/" + address
+		if direction == "received" {
+			authzPath = "/cosmos/authz/v1beta1/grants/grantee/" + address
+			feegrantPath = "/cosmos/feegrant/v1beta1/allowances/" + address
+		}
+
+		var grants authzGrantsResponse
+		found, err := queryGrants(client, authzPath, &grants)
+		if err != nil {
+			return nil, fmt.Errorf("cannot query the authz grants %s: %s", direction, err)
+		}
+		if found {
+			for _, g := range grants.Grants {
+				authorization, _ := g.Authorization["msg"].(string)
+				if authorization == "" {
+					authorization, _ = g.Authorization["@type"].(string)
+				}
+				entries = append(entries, GrantEntry{
+					Kind:          "authz",
+					Direction:     direction,
+					Granter:       g.Granter,
+					Grantee:       g.Grantee,
+					Authorization: authorization,
+					Expiration:    g.Expiration,
+				})
+			}
+		}
+
+		var allowances feegrantsResponse
+		found, err = queryGrants(client, feegrantPath, &allowances)
+		if err != nil {
+			return nil, fmt.Errorf("cannot query the fee allowances %s: %s", direction, err)
+		}
+		if found {
+			for _, a := range allowances.Allowances {
+				allowanceType, _ := a.Allowance["@type"].(string)
+				entries = append(entries, GrantEntry{
+					Kind:          "feegrant",
+					Direction:     direction,
+					Granter:       a.Granter,
+					Grantee:       a.Grantee,
+					Authorization: allowanceType,
+					Expiration:    allowanceExpiration(a.Allowance),
+				})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// the name of a msg type in the tx authz commands, eg. withdraw, if it has one
+func authzMsgName(msgType string) (string, bool) {
+	for name, t := range authzMsgTypes {
+		if t == msgType {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// list the authz grants and fee allowances given and received by a key, highlighting those
+// expiring within --days, and optionally push txs renewing the authz grants it gave
+func cmdGrants(cmd *cobra.Command, args []string) error {
+	chainName := args[0]
+	keyName := args[1]
+
+	if flagOutput != "table" && flagOutput != "json" {
+		return fmt.Errorf("invalid --output %q, must be table or json", flagOutput)
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	chain, found := conf.GetChain(chainName)
+	if !found {
+		return fmt.Errorf("chain %s not found in config", chainName)
+	}
+	key, found := conf.GetKey(keyName)
+	if !found {
+		return fmt.Errorf("key %s not found in config", keyName)
+	}
+	if flagREST != "" {
+		chain.REST = flagREST
+	}
+	if err := requireREST(chain); err != nil {
+		return err
+	}
+	address, err := bech32ify(key.Address, chain.Prefix)
+	if err != nil {
+		return err
+	}
+
+	entries, err := queryKeyGrants(newChainClient(chain), address)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, flagDays)
+	for i, entry := range entries {
+		expiration, err := time.Parse(time.RFC3339Nano, entry.Expiration)
+		entries[i].Expiring = err == nil && expiration.Before(cutoff)
+	}
+	// the ones expiring first on top, those without expiration last
+	sort.SliceStable(entries, func(i, j int) bool {
+		ei, erri := time.Parse(time.RFC3339Nano, entries[i].Expiration)
+		ej, errj := time.Parse(time.RFC3339Nano, entries[j].Expiration)
+		if erri != nil || errj != nil {
+			return erri == nil && errj != nil
+		}
+		return ei.Before(ej)
+	})
+
+	if flagOutput == "json" {
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if len(entries) == 0 {
+		fmt.Printf("no authz grants or fee allowances for %s on %s\n", keyName, chainName)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tDIRECTION\tGRANTER\tGRANTEE\tAUTHORIZATION\tEXPIRATION\t")
+		for _, entry := range entries {
+			expiring := ""
+			if entry.Expiring {
+				expiring = fmt.Sprintf("<- expires within %d days", flagDays)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Kind, entry.Direction, entry.Granter, entry.Grantee,
+				orDash(entry.Authorization), orDash(entry.Expiration), expiring)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if flagRenew <= 0 {
+		return nil
+	}
+	renewals := [][2]string{}
+	for _, entry := range entries {
+		if !entry.Expiring || entry.Direction != "given" {
+			continue
+		}
+		msgName, found := authzMsgName(entry.Authorization)
+		if entry.Kind != "authz" || !found {
+			fmt.Fprintf(os.Stderr, "cannot renew the %s %s grant to %s, please renew it by hand\n", entry.Kind, entry.Authorization, entry.Grantee)
+			continue
+		}
+		renewals = append(renewals, [2]string{entry.Grantee, msgName})
+	}
+	if len(renewals) == 0 {
+		return nil
+	}
+	if err := checkPushFees(cmd, conf, []string{chainName}); err != nil {
+		return err
+	}
+	for _, renewal := range renewals {
+		grantee, msgName := renewal[0], renewal[1]
+		description := fmt.Sprintf("renew %s grant to %s for %d days", msgName, grantee, flagRenew)
+		if err := pushQueued(chainName, keyName, description, func(opts pushOptions) error {
+			return grantAuthzTx(cmd, []string{chainName, keyName, grantee, msgName, fmt.Sprintf("%d", flagRenew)}, opts)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowanceExpiration(t *testing.T) {
+	cases := []struct {
+		name      string
+		allowance map[string]interface{}
+		want      string
+	}{
+		{name: "basic", allowance: map[string]interface{}{"@type": "/cosmos.feegrant.v1beta1.BasicAllowance", "expiration": "2024-06-01T00:00:00Z"}, want: "2024-06-01T00:00:00Z"},
+		{name: "periodic", allowance: map[string]interface{}{"@type": "/cosmos.feegrant.v1beta1.PeriodicAllowance", "basic": map[string]interface{}{"expiration": "2024-07-01T00:00:00Z"}}, want: "2024-07-01T00:00:00Z"},
+		{name: "allowed msgs of a periodic", allowance: map[string]interface{}{"@type": "/cosmos.feegrant.v1beta1.AllowedMsgAllowance", "allowance": map[string]interface{}{"basic": map[string]interface{}{"expiration": "2024-08-01T00:00:00Z"}}}, want: "2024-08-01T00:00:00Z"},
+		{name: "no expiration", allowance: map[string]interface{}{"@type": "/cosmos.feegrant.v1beta1.BasicAllowance", "expiration": nil}, want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := allowanceExpiration(tc.allowance); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryKeyGrants(t *testing.T) {
+	// grants by granter aren't served before cosmos-sdk v0.46, they are skipped
+	srv := newTestRoutes(t, map[string]string{
+		"/cosmos/authz/v1beta1/grants/grantee/cosmos1me": `{"grants":[` +
+			`{"granter":"cosmos1other","grantee":"cosmos1me","authorization":{"@type":"/cosmos.authz.v1beta1.GenericAuthorization","msg":"/cosmos.gov.v1beta1.MsgVote"},"expiration":"2024-06-01T00:00:00Z"},` +
+			`{"granter":"cosmos1other","grantee":"cosmos1me","authorization":{"@type":"/cosmos.bank.v1beta1.SendAuthorization","spend_limit":[]},"expiration":null}]}`,
+		"/cosmos/feegrant/v1beta1/issued/cosmos1me":     `{"allowances":[{"granter":"cosmos1me","grantee":"cosmos1bot","allowance":{"@type":"/cosmos.feegrant.v1beta1.PeriodicAllowance","basic":{"expiration":"2024-07-01T00:00:00Z"}}}]}`,
+		"/cosmos/feegrant/v1beta1/allowances/cosmos1me": `{"allowances":[]}`,
+	})
+	entries, err := queryKeyGrants(newChainClient(Chain{Name: "test", REST: srv.URL}), "cosmos1me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GrantEntry{
+		{Kind: "feegrant", Direction: "given", Granter: "cosmos1me", Grantee: "cosmos1bot", Authorization: "/cosmos.feegrant.v1beta1.PeriodicAllowance", Expiration: "2024-07-01T00:00:00Z"},
+		{Kind: "authz", Direction: "received", Granter: "cosmos1other", Grantee: "cosmos1me", Authorization: "/cosmos.gov.v1beta1.MsgVote", Expiration: "2024-06-01T00:00:00Z"},
+		{Kind: "authz", Direction: "received", Granter: "cosmos1other", Grantee: "cosmos1me", Authorization: "/cosmos.bank.v1beta1.SendAuthorization"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("got %+v, want %+v", entries, want)
+	}
+}
+
+func TestAuthzMsgName(t *testing.T) {
+	for name, msgType := range authzMsgTypes {
+		if got, found := authzMsgName(msgType); !found || got != name {
+			t.Fatalf("got %q for %s, want %q", got, msgType, name)
+		}
+	}
+	if _, found := authzMsgName("/cosmos.unknown.v1.MsgUnknown"); found {
+		t.Fatal("expected no name for an unknown msg type")
+	}
+}
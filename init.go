@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// interactively create a new config file
+func cmdInit(cmd *cobra.Command, args []string) error {
+	filename := flagConfigPath
+	if filename == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return err
+		}
+		filename = filepath.Join(usr.HomeDir, defaultGlobalConfigFile)
+	}
+
+	if _, err := os.Stat(filename); err == nil && !flagForce {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+
+	// a shared team config provides the keys, chains and bucket,
+	// so only the personal settings are asked for
+	var team *Config
+	if flagTeamConfig != "" {
+		b, err := ioutil.ReadFile(flagTeamConfig)
+		if err != nil {
+			return err
+		}
+		team = &Config{}
+		if err := toml.Unmarshal(b, team); err != nil {
+			return fmt.Errorf("cannot parse team config %s: %s", flagTeamConfig, err)
+		}
+		migrateNodes(team.Chains)
+		fmt.Printf("using the %d keys and %d chains of %s\n", len(team.Keys), len(team.Chains), flagTeamConfig)
+	}
+
+	conf, err := promptConfig(bufio.NewReader(os.Stdin), team)
+	if err != nil {
+		return err
+	}
+
+	return writeConfig(filename, conf)
+}
+
+// ask for the settings of a new config. With a team config, the keys and chains are read from it
+// in the bucket through the teamconfig setting, so only their personal fields are asked for
+func promptConfig(in *bufio.Reader, team *Config) (*Config, error) {
+	var err error
+	conf := &Config{DefaultGas: int64(defaultGas)}
+
+	fmt.Println("########################")
+	fmt.Println("# General configuration")
+	fmt.Println("########################")
+	if conf.User, err = promptRequired(in, "user name, eg. initials - for naming your signatures", ""); err != nil {
+		return nil, err
+	}
+	conf.KeyringBackend = prompt(in, "keyring backend (os, file, test)", "os")
+
+	fmt.Println("########################")
+	fmt.Println("# Storage")
+	fmt.Println("########################")
+	if team != nil && team.AWS.Bucket != "" {
+		conf.AWS.Address = team.AWS.Address
+		conf.AWS.Bucket = team.AWS.Bucket
+		conf.AWS.BucketRegion = team.AWS.BucketRegion
+		fmt.Printf("using bucket %s from the team config\n", conf.AWS.Bucket)
+	} else {
+		conf.AWS.Address = prompt(in, "custom S3 address for self-hosted storage, empty for AWS S3", "")
+		if conf.AWS.Bucket, err = promptRequired(in, "bucket name", ""); err != nil {
+			return nil, err
+		}
+		conf.AWS.BucketRegion = prompt(in, "bucket region", defaultBucketRegion)
+	}
+	conf.AWS.Pub = prompt(in, "access key id, empty to use the standard aws credential chain (env vars, ~/.aws profiles, roles)", "")
+	if conf.AWS.Pub != "" {
+		if conf.AWS.Priv, err = promptSecret(in, "secret access key"); err != nil {
+			return nil, err
+		}
+	} else {
+		conf.AWS.Profile = prompt(in, "aws profile, empty for the default one", "")
+	}
+	if team != nil {
+		conf.TeamConfig = prompt(in, "name of the team config in the bucket", defaultTeamConfig)
+	}
+
+	fmt.Println("########################")
+	fmt.Println("# Multisig keys")
+	fmt.Println("########################")
+	if team != nil {
+		// only the localname is kept, the rest of the key comes from the team config
+		for _, key := range team.Keys {
+			localName := prompt(in, fmt.Sprintf("name of key %s in your local keystore", key.Name), key.Name)
+			if localName != key.Name {
+				conf.Keys = append(conf.Keys, Key{Name: key.Name, LocalName: localName})
+			}
+		}
+	}
+	for promptYesNo(in, "add a multisig key?") {
+		key, err := promptKey(in)
+		if err != nil {
+			return nil, err
+		}
+		conf.Keys = append(conf.Keys, key)
+	}
+
+	fmt.Println("########################")
+	fmt.Println("# Chains")
+	fmt.Println("########################")
+	if team != nil && len(team.Members) > 0 {
+		fmt.Printf("not importing the %d [[members]] of the team config, check their pubkeys with each member and add them by hand\n", len(team.Members))
+	}
+	for promptYesNo(in, "add a chain?") {
+		chain, err := promptChain(in, conf)
+		if err != nil {
+			if stdinClosed(in) {
+				return nil, err
+			}
+			fmt.Println(err)
+			continue
+		}
+		conf.Chains = append(conf.Chains, chain)
+	}
+
+	return conf, nil
+}
+
+func promptKey(in *bufio.Reader) (Key, error) {
+	var err error
+	key := Key{}
+	if key.Name, err = promptRequired(in, "name of the multisig key - same for everyone", ""); err != nil {
+		return Key{}, err
+	}
+	for {
+		if key.Address, err = promptRequired(in, "bech32 address of the key - any prefix", ""); err != nil {
+			return Key{}, err
+		}
+		_, err := bech32ify(key.Address, "cosmos")
+		if err == nil {
+			break
+		}
+		if stdinClosed(in) {
+			return Key{}, fmt.Errorf("invalid bech32 address %q for key %s: %s", key.Address, key.Name, err)
+		}
+		fmt.Printf("invalid bech32 address: %s\n", err)
+	}
+	key.LocalName = prompt(in, "name of this key in your local keystore", key.Name)
+	return key, nil
+}
+
+func promptChain(in *bufio.Reader, conf *Config) (Chain, error) {
+	registryName := prompt(in, "name of the chain in the chain-registry, empty to enter it manually", "")
+	if registryName != "" {
+		info, err := loadChainInfo(conf, registryName)
+		if err != nil {
+			return Chain{}, fmt.Errorf("cannot find %s in the chain registry: %s", registryName, err)
+		}
+		chain := chainFromRegistry(prompt(in, "name of the chain", registryName), info)
+		chain.RPC = prompt(in, "rpc endpoint", chain.RPC)
+		chain.REST = prompt(in, "rest endpoint", chain.REST)
+		return chain, nil
+	}
+
+	var err error
+	chain := Chain{}
+	if chain.Name, err = promptRequired(in, "name of the chain", ""); err != nil {
+		return Chain{}, err
+	}
+	if chain.Binary, err = promptRequired(in, "name of the binary", ""); err != nil {
+		return Chain{}, err
+	}
+	if chain.Prefix, err = promptRequired(in, "bech32 prefix", ""); err != nil {
+		return Chain{}, err
+	}
+	if chain.ID, err = promptRequired(in, "chain id", ""); err != nil {
+		return Chain{}, err
+	}
+	chain.Denom = prompt(in, "native denom", "")
+	chain.GasPrice = prompt(in, "gas price, eg. 0.025uatom", "")
+	chain.RPC = prompt(in, "rpc endpoint", "")
+	chain.REST = prompt(in, "rest endpoint", "")
+	chain.GRPC = prompt(in, "grpc endpoint, optional", "")
+	return chain, nil
+}
+
+// ask a question, returning the default if the answer is empty
+func prompt(in *bufio.Reader, question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, _ := in.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// ask for a secret without echoing it if stdin is a terminal
+func promptSecret(in *bufio.Reader, question string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(in, question, ""), nil
+	}
+	fmt.Printf("%s: ", question)
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("cannot read the %s: %s", question, err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// ask a question until it gets a non-empty answer, failing if there is nothing left to read
+func promptRequired(in *bufio.Reader, question, def string) (string, error) {
+	for {
+		if answer := prompt(in, question, def); answer != "" {
+			return answer, nil
+		}
+		if stdinClosed(in) {
+			fmt.Println()
+			return "", fmt.Errorf("no answer for the %s, aborting", question)
+		}
+	}
+}
+
+// don't loop forever on questions if there is nothing left to read
+func stdinClosed(in *bufio.Reader) bool {
+	_, err := in.Peek(1)
+	return err != nil
+}
+
+func promptYesNo(in *bufio.Reader, question string) bool {
+	answer := strings.ToLower(prompt(in, question+" (y/n)", "n"))
+	return answer == "y" || answer == "yes"
+}
+
+// write the config file, readable by the current user only as it holds credentials
+func writeConfig(filename string, conf *Config) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# multisig config, see data/config.toml in the repository for documentation\n\n")
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(conf); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filename, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	// WriteFile doesn't change the permissions of an existing file
+	if err := os.Chmod(filename, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s\n", filename)
+	return nil
+}
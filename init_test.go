@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.toml")
+	conf := &Config{
+		User:           "me",
+		KeyringBackend: "test",
+		AWS:            AWS{Bucket: "bucket", BucketRegion: defaultBucketRegion, Priv: "secret"},
+		Keys:           []Key{{Name: "validator", Address: "cosmos1abc"}},
+		Chains:         []Chain{{Name: "cosmoshub", Binary: "gaiad", Prefix: "cosmos", ID: "cosmoshub-4"}},
+	}
+	if err := writeConfig(filename, conf); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("got permissions %o, want 600", info.Mode().Perm())
+	}
+
+	loaded, err := loadLocalConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.User != "me" || loaded.AWS.Priv != "secret" || len(loaded.Keys) != 1 || len(loaded.Chains) != 1 || len(loaded.AWS.Encryption.Recipients) != 0 {
+		t.Fatalf("got %+v", loaded)
+	}
+
+	// with encryption recipients
+	conf.AWS.Encryption.Recipients = []string{"AAAA"}
+	if err := writeConfig(filename, conf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := loadLocalConfig(filename); err != nil || len(loaded.AWS.Encryption.Recipients) != 1 {
+		t.Fatalf("got %+v and %v", loaded, err)
+	}
+}
+
+func TestPromptRequired(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("\n\nme\n"))
+	if answer, err := promptRequired(in, "user name", ""); err != nil || answer != "me" {
+		t.Fatalf("got %q and %v, want me", answer, err)
+	}
+	if answer, err := promptRequired(in, "bucket name", ""); err == nil {
+		t.Fatalf("got %q, want an error at the end of input", answer)
+	}
+}
+
+func TestPromptConfig(t *testing.T) {
+	team := &Config{
+		AWS:    AWS{Bucket: "team-bucket", BucketRegion: "eu-west-1"},
+		Keys:   []Key{{Name: "validator", Address: "cosmos1abc"}, {Name: "treasury", Address: "cosmos1def"}},
+		Chains: []Chain{{Name: "cosmoshub", Binary: "gaiad", Prefix: "cosmos", ID: "cosmoshub-4"}},
+	}
+	// user, keyring backend, access key id, profile, team config, localnames, no more keys or chains
+	input := "me\ntest\n\n\n\nval-local\n\nn\nn\n"
+	conf, err := promptConfig(bufio.NewReader(strings.NewReader(input)), team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.User != "me" || conf.KeyringBackend != "test" || conf.AWS.Bucket != "team-bucket" || conf.TeamConfig != defaultTeamConfig {
+		t.Fatalf("got %+v", conf)
+	}
+	// the shared keys and chains are read from the team config, only differing localnames are kept
+	if len(conf.Keys) != 1 || conf.Keys[0] != (Key{Name: "validator", LocalName: "val-local"}) || len(conf.Chains) != 0 {
+		t.Fatalf("got keys %+v and chains %+v", conf.Keys, conf.Chains)
+	}
+
+	// the input ends before the bucket name
+	if _, err := promptConfig(bufio.NewReader(strings.NewReader("me\ntest\n\n")), nil); err == nil {
+		t.Fatal("expected an error without a bucket name")
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	kmultisig "github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/spf13/cobra"
+)
+
+var (
+	legacyAminoPubKeyType = "/cosmos.crypto.multisig.LegacyAminoPubKey"
+	secp256k1PubKeyType   = "/cosmos.crypto.secp256k1.PubKey"
+)
+
+// MultisigPubKey is the threshold and the public keys of the members of a multisig, in order
+type MultisigPubKey struct {
+	Threshold  int
+	PublicKeys []string // base64 secp256k1 public keys
+}
+
+// the address of the multisig, which depends on the order of the public keys
+func (m MultisigPubKey) address() ([]byte, error) {
+	if m.Threshold <= 0 || m.Threshold > len(m.PublicKeys) {
+		return nil, fmt.Errorf("invalid threshold %d for %d public keys", m.Threshold, len(m.PublicKeys))
+	}
+	pubKeys := []cryptotypes.PubKey{}
+	for _, k := range m.PublicKeys {
+		b, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(b) != secp256k1.PubKeySize {
+			return nil, fmt.Errorf("invalid secp256k1 public key %q", k)
+		}
+		pubKeys = append(pubKeys, &secp256k1.PubKey{Key: b})
+	}
+	return kmultisig.NewLegacyAminoPubKey(m.Threshold, pubKeys).Address(), nil
+}
+
+// sort the public keys by address, like `keys add --multisig` does without --nosort
+func (m *MultisigPubKey) sort() error {
+	addresses := map[string][]byte{}
+	for _, k := range m.PublicKeys {
+		b, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(b) != secp256k1.PubKeySize {
+			return fmt.Errorf("invalid secp256k1 public key %q", k)
+		}
+		addresses[k] = (&secp256k1.PubKey{Key: b}).Address()
+	}
+	sort.SliceStable(m.PublicKeys, func(i, j int) bool {
+		return bytes.Compare(addresses[m.PublicKeys[i]], addresses[m.PublicKeys[j]]) < 0
+	})
+	return nil
+}
+
+// check the multisig has the address of a key in the config
+func (m MultisigPubKey) matches(key Key) error {
+	addr, err := m.address()
+	if err != nil {
+		return err
+	}
+	_, keyAddr, err := bech32.DecodeAndConvert(key.Address)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(addr, keyAddr) {
+		return fmt.Errorf("the multisig found has a different address than %s in the config", key.Name)
+	}
+	return nil
+}
+
+// look for an object of the given @type in the json output of a binary, eg. of `keys show`
+// or `query account`, where it can also be a json string
+func findTyped(v interface{}, typ string) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		if !strings.HasPrefix(strings.TrimSpace(v), "{") {
+			return nil, false
+		}
+		var inner interface{}
+		if err := json.Unmarshal([]byte(v), &inner); err != nil {
+			return nil, false
+		}
+		return findTyped(inner, typ)
+	case []interface{}:
+		for _, item := range v {
+			if found, ok := findTyped(item, typ); ok {
+				return found, true
+			}
+		}
+	case map[string]interface{}:
+		if v["@type"] == typ {
+			return v, true
+		}
+		for _, item := range v {
+			if found, ok := findTyped(item, typ); ok {
+				return found, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func parseMultisigPubKey(b []byte) (*MultisigPubKey, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	pk, found := findTyped(v, legacyAminoPubKeyType)
+	if !found {
+		return nil, fmt.Errorf("no multisig public key found")
+	}
+
+	threshold, err := strconv.Atoi(fmt.Sprint(pk["threshold"]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid multisig threshold %v", pk["threshold"])
+	}
+	m := &MultisigPubKey{Threshold: threshold}
+	publicKeys, _ := pk["public_keys"].([]interface{})
+	for _, memberPk := range publicKeys {
+		memberPkMap, _ := memberPk.(map[string]interface{})
+		key, ok := memberPkMap["key"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid multisig public key")
+		}
+		m.PublicKeys = append(m.PublicKeys, key)
+	}
+	return m, nil
+}
+
+// parse the base64 secp256k1 public key in the json output of a binary,
+// skipping anything it printed before the json, eg. when reading a signature
+func parsePubKey(b []byte) (string, error) {
+	start := bytes.IndexByte(b, '{')
+	if start < 0 {
+		return "", fmt.Errorf("no json found")
+	}
+	var v interface{}
+	if err := json.NewDecoder(bytes.NewReader(b[start:])).Decode(&v); err != nil {
+		return "", err
+	}
+	pk, found := findTyped(v, secp256k1PubKeyType)
+	if !found {
+		return "", fmt.Errorf("no secp256k1 public key found")
+	}
+	key, ok := pk["key"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid secp256k1 public key")
+	}
+	return key, nil
+}
+
+// the keyring backend and home args for the keystore of a chain's binary
+func keystoreArgs(conf *Config, chain Chain) ([]string, error) {
+	args := []string{}
+	if backend := conf.GetKeyringBackend(chain); backend != "" {
+		args = append(args, "--keyring-backend", backend)
+	}
+	home, err := chainHome(chain)
+	if err != nil {
+		return nil, err
+	}
+	if home != "" {
+		args = append(args, "--home", home)
+	}
+	return args, nil
+}
+
+// the local name of a multisig key, defaults to its name
+func keyLocalName(key Key) string {
+	if key.LocalName != "" {
+		return key.LocalName
+	}
+	return key.Name
+}
+
+// read a multisig from the keystore of a chain's binary
+func readKeystoreMultisig(conf *Config, chain Chain, localName string) (*MultisigPubKey, error) {
+	ksArgs, err := keystoreArgs(conf, chain)
+	if err != nil {
+		return nil, err
+	}
+	cmdArgs := append([]string{"keys", "show", localName, "--output", "json"}, ksArgs...)
+	cmd := exec.Command(chain.Binary, cmdArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	b, err := cmd.Output()
+	if err != nil {
+		fmt.Println(cmd)
+		return nil, fmt.Errorf("cannot read %s from the %s keystore: %s", localName, chain.Binary, err)
+	}
+	m, err := parseMultisigPubKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s in the %s keystore is not a multisig: %s", localName, chain.Binary, err)
+	}
+	return m, nil
+}
+
+// read a multisig from its account on a chain, which only has a public key once it sent a tx
+func queryChainMultisig(chain Chain, key Key) (*MultisigPubKey, error) {
+	address, err := bech32ify(key.Address, chain.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := newChainClient(chain).AccountJSON(address)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseMultisigPubKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("the account of %s on %s has no multisig public key, it may not have sent a tx yet", key.Name, chain.Name)
+	}
+	return m, nil
+}
+
+// run a keys command with the binary of a chain, letting it prompt for the keyring passphrase
+func runKeysCmd(conf *Config, chain Chain, cmdArgs ...string) error {
+	ksArgs, err := keystoreArgs(conf, chain)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(chain.Binary, append(cmdArgs, ksArgs...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	fmt.Println(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %s", cmd, err)
+	}
+	return nil
+}
+
+// add a multisig to the keystore of a chain's binary under localName,
+// with its members as <localName>-<i>, keeping the order of the public keys
+func importMultisig(conf *Config, chain Chain, localName string, m *MultisigPubKey) error {
+	memberNames := []string{}
+	for i, k := range m.PublicKeys {
+		memberName := fmt.Sprintf("%s-%d", localName, i)
+		pubKey := fmt.Sprintf(`{"@type":%q,"key":%q}`, secp256k1PubKeyType, k)
+		if err := runKeysCmd(conf, chain, "keys", "add", memberName, "--pubkey", pubKey); err != nil {
+			return err
+		}
+		memberNames = append(memberNames, memberName)
+	}
+
+	// --nosort keeps the order of the public keys, on which the address depends
+	return runKeysCmd(conf, chain, "keys", "add", localName,
+		"--multisig", strings.Join(memberNames, ","),
+		"--multisig-threshold", fmt.Sprintf("%d", m.Threshold),
+		"--nosort")
+}
+
+// copy a multisig from the keystore of one chain's binary to another's
+func cmdKeysPort(cmd *cobra.Command, args []string) error {
+	keyName := args[0]
+	fromChainName := args[1]
+	toChainName := args[2]
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	key, found := conf.GetKey(keyName)
+	if !found {
+		return fmt.Errorf("key %s not found in config", keyName)
+	}
+	fromChain, found := conf.GetChain(fromChainName)
+	if !found {
+		return fmt.Errorf("chain %s not found in config", fromChainName)
+	}
+	toChain, found := conf.GetChain(toChainName)
+	if !found {
+		return fmt.Errorf("chain %s not found in config", toChainName)
+	}
+
+	localName := keyLocalName(key)
+
+	var m *MultisigPubKey
+	if flagQuery {
+		if flagREST != "" {
+			fromChain.REST = flagREST
+		}
+		m, err = queryChainMultisig(fromChain, key)
+	} else {
+		m, err = readKeystoreMultisig(conf, fromChain, localName)
+	}
+	if err != nil {
+		return err
+	}
+
+	// make sure we import the right multisig
+	if err := m.matches(key); err != nil {
+		return err
+	}
+	fmt.Printf("found %s: %d of %d multisig\n", key.Name, m.Threshold, len(m.PublicKeys))
+
+	if err := importMultisig(conf, toChain, localName, m); err != nil {
+		return err
+	}
+	fmt.Printf("added %s to the %s keystore as %s\n", key.Name, toChain.Binary, localName)
+	return nil
+}
+
+// create a new multisig from the public keys of its members, add it to the config,
+// and optionally to the keystores of all the chains' binaries
+func cmdKeysCreate(cmd *cobra.Command, args []string) error {
+	keyName := args[0]
+
+	filename, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	conf, err := loadConfig(filename)
+	if err != nil {
+		return err
+	}
+	if _, found := conf.GetKey(keyName); found {
+		return fmt.Errorf("key %s already exists in %s", keyName, filename)
+	}
+
+	// members can be given by their public key or by their name in the registry
+	publicKeys, err := resolveMemberPubKeys(awsSession(conf.AWS), conf, flagMembers)
+	if err != nil {
+		return err
+	}
+	m := &MultisigPubKey{Threshold: flagThreshold, PublicKeys: publicKeys}
+	if !flagNoSort {
+		if err := m.sort(); err != nil {
+			return err
+		}
+	}
+	addr, err := m.address()
+	if err != nil {
+		return err
+	}
+	address, err := bech32.ConvertAndEncode("cosmos", addr)
+	if err != nil {
+		return err
+	}
+
+	key := Key{
+		Name:      keyName,
+		Address:   address,
+		Threshold: m.Threshold,
+	}
+	// the key is imported under its own name in the keystores
+	if flagImport {
+		key.LocalName = keyName
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n# %d of %d multisig created with `multisig keys create`, members in order:\n", m.Threshold, len(m.PublicKeys))
+	for _, k := range m.PublicKeys {
+		fmt.Fprintf(&buf, "#   %s\n", k)
+	}
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Keys []Key `toml:"keys"`
+	}{[]Key{key}}); err != nil {
+		return err
+	}
+
+	fmt.Print(buf.String())
+	if flagDryRun {
+		return nil
+	}
+	if err := appendToConfig(filename, buf.Bytes()); err != nil {
+		return err
+	}
+	fmt.Printf("added key %s to %s\n", keyName, filename)
+	if conf.TeamConfig != "" {
+		fmt.Println("add it to the team config too, see `multisig config push`")
+	}
+
+	if flagImport {
+		// chains with the same binary, home and keyring backend share a keystore, which can only hold the key once
+		imported := map[[3]string]bool{}
+		for _, chain := range conf.Chains {
+			home, err := chainHome(chain)
+			if err != nil {
+				return err
+			}
+			keystore := [3]string{chain.Binary, home, conf.GetKeyringBackend(chain)}
+			if imported[keystore] {
+				continue
+			}
+			if err := importMultisig(conf, chain, keyName, m); err != nil {
+				return err
+			}
+			imported[keystore] = true
+			fmt.Printf("added %s to the %s keystore\n", keyName, chain.Binary)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// base64 secp256k1 public keys of n new keys
+func newTestPubKeys(n int) []string {
+	keys := []string{}
+	for i := 0; i < n; i++ {
+		keys = append(keys, base64.StdEncoding.EncodeToString(secp256k1.GenPrivKey().PubKey().Bytes()))
+	}
+	return keys
+}
+
+func TestMultisigPubKeySortAddress(t *testing.T) {
+	keys := newTestPubKeys(3)
+	sorted := MultisigPubKey{Threshold: 2, PublicKeys: append([]string{}, keys...)}
+	if err := sorted.sort(); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := sorted.address()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sorting makes the address independent of the order the keys are given in
+	for _, order := range [][]string{{keys[2], keys[1], keys[0]}, {keys[1], keys[0], keys[2]}} {
+		m := MultisigPubKey{Threshold: 2, PublicKeys: order}
+		if err := m.sort(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.address()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, addr) {
+			t.Fatalf("got address %X for order %v, want %X", got, order, addr)
+		}
+	}
+
+	// without sorting the order matters, like with --nosort
+	reversed := MultisigPubKey{Threshold: 2, PublicKeys: []string{sorted.PublicKeys[2], sorted.PublicKeys[1], sorted.PublicKeys[0]}}
+	if got, err := reversed.address(); err != nil || bytes.Equal(got, addr) {
+		t.Fatalf("got address %X and %v for the reversed keys, want another address", got, err)
+	}
+	// and so does the threshold
+	if got, err := (MultisigPubKey{Threshold: 3, PublicKeys: sorted.PublicKeys}).address(); err != nil || bytes.Equal(got, addr) {
+		t.Fatalf("got address %X and %v for another threshold, want another address", got, err)
+	}
+
+	bech, err := bech32.ConvertAndEncode("cosmos", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sorted.matches(Key{Name: "validator", Address: bech}); err != nil {
+		t.Fatalf("multisig doesn't match its own address: %s", err)
+	}
+	if err := reversed.matches(Key{Name: "validator", Address: bech}); err == nil {
+		t.Fatal("expected the reversed multisig not to match")
+	}
+}
+
+func TestMultisigPubKeyInvalid(t *testing.T) {
+	keys := newTestPubKeys(2)
+	cases := []struct {
+		name string
+		m    MultisigPubKey
+	}{
+		{name: "zero threshold", m: MultisigPubKey{Threshold: 0, PublicKeys: keys}},
+		{name: "threshold above keys", m: MultisigPubKey{Threshold: 3, PublicKeys: keys}},
+		{name: "not base64", m: MultisigPubKey{Threshold: 1, PublicKeys: []string{keys[0], "not base64!"}}},
+		{name: "wrong size", m: MultisigPubKey{Threshold: 1, PublicKeys: []string{keys[0], base64.StdEncoding.EncodeToString([]byte("short"))}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.m.address(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+	if err := (&MultisigPubKey{Threshold: 1, PublicKeys: []string{"not base64!"}}).sort(); err == nil {
+		t.Fatal("expected sort to fail on an invalid key")
+	}
+}
+
+func TestParseMultisigPubKey(t *testing.T) {
+	cases := []struct {
+		name          string
+		output        string
+		wantThreshold int
+		wantKeys      []string
+		wantErr       bool
+	}{
+		{
+			name:          "keys show",
+			output:        `{"name":"validator","type":"multi","address":"cosmos1abc","pubkey":"{\"@type\":\"/cosmos.crypto.multisig.LegacyAminoPubKey\",\"threshold\":2,\"public_keys\":[{\"@type\":\"/cosmos.crypto.secp256k1.PubKey\",\"key\":\"A1\"},{\"@type\":\"/cosmos.crypto.secp256k1.PubKey\",\"key\":\"B2\"}]}"}`,
+			wantThreshold: 2, wantKeys: []string{"A1", "B2"},
+		},
+		{
+			name:          "query account",
+			output:        `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","pub_key":{"@type":"/cosmos.crypto.multisig.LegacyAminoPubKey","threshold":"3","public_keys":[{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"C3"}]}}}`,
+			wantThreshold: 3, wantKeys: []string{"C3"},
+		},
+		{name: "single key", output: `{"pubkey":{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"A1"}}`, wantErr: true},
+		{name: "invalid threshold", output: `{"@type":"/cosmos.crypto.multisig.LegacyAminoPubKey","threshold":"two","public_keys":[]}`, wantErr: true},
+		{name: "invalid member key", output: `{"@type":"/cosmos.crypto.multisig.LegacyAminoPubKey","threshold":1,"public_keys":[{"key":1}]}`, wantErr: true},
+		{name: "not json", output: `validator multi cosmos1abc`, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMultisigPubKey([]byte(tc.output))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if m.Threshold != tc.wantThreshold || len(m.PublicKeys) != len(tc.wantKeys) {
+				t.Fatalf("got %+v, want threshold %d and keys %v", m, tc.wantThreshold, tc.wantKeys)
+			}
+			for i, key := range tc.wantKeys {
+				if m.PublicKeys[i] != key {
+					t.Fatalf("got keys %v, want %v", m.PublicKeys, tc.wantKeys)
+				}
+			}
+		})
+	}
+}
@@ -6,9 +6,6 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 func cmdList(cmd *cobra.Command, args []string) error {
@@ -24,17 +21,22 @@ func listAll() error {
 		return err
 	}
 	sess := awsSession(conf.AWS)
-	svc := s3.New(sess)
 
 	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.AWS.Bucket)})
+	objects, err := awsListObjects(sess, conf.AWS, "")
 	if err != nil {
 		return err
 	}
 
+	// archived txs are listed with `multisig archive list`, and locks, member records
+	// and the team config aren't txs
 	files := []string{}
-	for _, item := range resp.Contents {
+	for _, item := range objects {
 		key := *item.Key
+		if strings.HasPrefix(key, archiveDir+"/") || strings.HasPrefix(key, locksDir+"/") ||
+			strings.HasPrefix(key, membersDir+"/") || key == teamConfigObject(conf) {
+			continue
+		}
 		files = append(files, key)
 	}
 
@@ -74,21 +76,17 @@ func listDir(args []string) error {
 		return err
 	}
 	sess := awsSession(conf.AWS)
-	svc := s3.New(sess)
-	filePath := filepath.Join(chainName, keyName)
+	filePath := filepath.Join(chainName, keyName) + "/"
 
-	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.AWS.Bucket)})
+	// list all items in our folder
+	objects, err := awsListObjects(sess, conf.AWS, filePath)
 	if err != nil {
 		return err
 	}
 
 	files := []string{}
-	for _, item := range resp.Contents {
-		key := *item.Key
-		if strings.HasPrefix(key, filePath) {
-			files = append(files, key)
-		}
+	for _, item := range objects {
+		files = append(files, *item.Key)
 	}
 
 	for _, f := range files {
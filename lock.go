@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var (
+	// locks are kept outside of the chain/key directories so they aren't mistaken for txs
+	locksDir = "locks"
+
+	// how long a lock is held before others may take it over, eg. if the holder crashed
+	lockLease = 2 * time.Minute
+
+	// broadcasts hold the lock while signing, waiting for the tx to be included and archiving it
+	broadcastLockLease = 10 * time.Minute
+)
+
+// Lock on the tx queue of a chain/key pair, held while allocating an index and pushing a tx
+type Lock struct {
+	Owner   string `json:"owner"`
+	Token   string `json:"token"`
+	Expires string `json:"expires"`
+}
+
+func (l Lock) expired() bool {
+	expires, err := time.Parse(time.RFC3339, l.Expires)
+	return err != nil || time.Now().After(expires)
+}
+
+// acquire the lock on the tx queue of chainName/keyName for lockLease, returning a function to release it
+func acquireLock(sess *session.Session, conf *Config, chainName, keyName string) (func(), error) {
+	return acquireLockFor(sess, conf, chainName, keyName, lockLease)
+}
+
+// acquire the lock on the tx queue of chainName/keyName for lease, returning a function to release it.
+// The lock object is created with a conditional put (If-None-Match), and an expired lock is
+// taken over with a conditional put on its etag (If-Match), so only one of two concurrent
+// pushers can get it. Since not all S3-compatible storage supports conditional puts,
+// the lock is read back to make sure we hold it
+func acquireLockFor(sess *session.Session, conf *Config, chainName, keyName string, lease time.Duration) (func(), error) {
+	lockKey := filepath.Join(locksDir, chainName, keyName+".json")
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, err
+	}
+	lock := Lock{
+		Owner:   conf.User,
+		Token:   hex.EncodeToString(tokenBytes),
+		Expires: time.Now().Add(lease).UTC().Format(time.RFC3339),
+	}
+	lockBytes, err := json.Marshal(lock)
+	if err != nil {
+		return nil, err
+	}
+
+	err = awsPutConditional(sess, conf.AWS, lockKey, lockBytes, "")
+	if isPreconditionFailed(err) {
+		existing, etag, err2 := getLock(sess, conf.AWS, lockKey)
+		if err2 != nil {
+			return nil, err2
+		}
+		switch {
+		case existing == nil:
+			// released in the meantime
+			err = awsPutConditional(sess, conf.AWS, lockKey, lockBytes, "")
+		case !existing.expired():
+			return nil, lockContentionError(chainName, keyName, existing)
+		default:
+			fmt.Printf("taking over the expired lock of %s on %s/%s\n", existing.Owner, chainName, keyName)
+			err = awsPutConditional(sess, conf.AWS, lockKey, lockBytes, etag)
+		}
+	}
+	if isPreconditionFailed(err) {
+		existing, _, _ := getLock(sess, conf.AWS, lockKey)
+		return nil, lockContentionError(chainName, keyName, existing)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot lock %s/%s: %s", chainName, keyName, err)
+	}
+
+	// make sure the storage didn't silently ignore the condition
+	current, _, err := getLock(sess, conf.AWS, lockKey)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.Token != lock.Token {
+		return nil, lockContentionError(chainName, keyName, current)
+	}
+
+	release := func() {
+		current, _, err := getLock(sess, conf.AWS, lockKey)
+		if err != nil || current == nil || current.Token != lock.Token {
+			return
+		}
+		if err := awsDelete(sess, conf.AWS, lockKey); err != nil {
+			fmt.Printf("WARNING: cannot release the lock %s, it will expire at %s: %s\n", lockKey, lock.Expires, err)
+		}
+	}
+	return release, nil
+}
+
+func lockContentionError(chainName, keyName string, lock *Lock) error {
+	if lock == nil {
+		return fmt.Errorf("someone else is pushing to %s/%s at the same time, please try again", chainName, keyName)
+	}
+	return fmt.Errorf("%s is pushing to %s/%s at the same time (lock held until %s), please try again", lock.Owner, chainName, keyName, lock.Expires)
+}
+
+// read a lock and its etag, or nil if there is none
+func getLock(sess *session.Session, conf AWS, lockKey string) (*Lock, string, error) {
+	svc := s3.New(sess)
+	out, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(conf.Bucket),
+		Key:    aws.String(lockKey),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	b, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var lock Lock
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, "", fmt.Errorf("cannot parse lock %s: %s", lockKey, err)
+	}
+	return &lock, aws.StringValue(out.ETag), nil
+}
+
+// put an object only if it doesn't exist yet, or if ifMatch is set, only if its etag matches
+func awsPutConditional(sess *session.Session, conf AWS, objName string, dataBytes []byte, ifMatch string) error {
+	svc := s3.New(sess)
+	req, _ := svc.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(conf.Bucket),
+		Key:    aws.String(objName),
+		Body:   bytes.NewReader(dataBytes),
+	})
+	// the version of the sdk we use predates conditional puts, so set the headers ourselves
+	if ifMatch == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", ifMatch)
+	}
+	return req.Send()
+}
+
+func isPreconditionFailed(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() == http.StatusPreconditionFailed || rerr.StatusCode() == http.StatusConflict
+	}
+	return false
+}
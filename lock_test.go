@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	alice := &Config{User: "alice", AWS: bucket}
+	bob := &Config{User: "bob", AWS: bucket}
+	lockKey := "locks/cosmoshub/validator.json"
+
+	releaseAlice, err := acquireLock(sess, alice, "cosmoshub", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireLock(sess, bob, "cosmoshub", "validator"); err == nil || !strings.Contains(err.Error(), "alice is pushing to cosmoshub/validator") {
+		t.Fatalf("got error %v, want the lock held by alice", err)
+	}
+	// the locks of other chain/key pairs are independent
+	releaseOther, err := acquireLock(sess, bob, "osmosis", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseOther()
+
+	releaseAlice()
+	if _, found := objects[lockKey]; found {
+		t.Fatal("the lock was not released")
+	}
+	releaseBob, err := acquireLock(sess, bob, "cosmoshub", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// releasing a lock taken over by someone else leaves it alone
+	releaseAlice()
+	if _, found := objects[lockKey]; !found {
+		t.Fatal("bob's lock was released by alice")
+	}
+	releaseBob()
+}
+
+func TestAcquireLockExpired(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	lockKey := "locks/cosmoshub/validator.json"
+
+	// alice crashed while holding the lock
+	expired, _ := json.Marshal(Lock{Owner: "alice", Token: "abcd", Expires: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)})
+	objects[lockKey] = expired
+
+	release, err := acquireLock(sess, &Config{User: "bob", AWS: bucket}, "cosmoshub", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock Lock
+	if err := json.Unmarshal(objects[lockKey], &lock); err != nil || lock.Owner != "bob" || lock.expired() {
+		t.Fatalf("got lock %+v and %v, want it held by bob", lock, err)
+	}
+	release()
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"cosmossdk.io/math"
 	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/aws/session"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/spf13/cobra"
 	"io"
@@ -26,18 +25,45 @@ var (
 	defaultLocalConfigFile  = "config.toml"
 	defaultGlobalConfigFile = ".multisig/config.toml"
 
+	// local copy of the chain-registry, populated by `multisig registry sync`
+	defaultRegistryDir = ".multisig/registry"
+
 	// files for signing - we use these filenames in the local working directory and in the remote bucket
 	unsignedJSON = "unsigned.json"
 	signedJSON   = "signed.json"
 	signDataJSON = "signdata.json"
+	manifestJSON = "manifest.json"
+
+	// how long to wait for a broadcast tx to be included in a block
+	txInclusionTimeout = time.Minute
 )
 
+// how a tx is pushed: the tx commands take it from their flags, while the commands
+// pushing txs from a report (eg. rewards --push) set it for each tx
+type pushOptions struct {
+	additional  bool      // add the tx after the pending txs
+	description string    // information about the tx
+	out         io.Writer // where the generated tx and progress are printed
+}
+
+// the push options given by the flags of the tx commands
+func flagPushOptions() pushOptions {
+	return pushOptions{additional: flagAdditional, description: flagDescription, out: os.Stdout}
+}
+
 // SignData Data we need for signers to sign a tx (eg. without access to a node)
 type SignData struct {
 	Account     int    `json:"account"`
 	Sequence    int    `json:"sequence"`
 	ChainID     string `json:"chain-id"`
 	Description string `json:"description"`
+
+	// unordered txs (cosmos-sdk v0.53+) use sequence 0 and expire at their timeout instead
+	Unordered        bool   `json:"unordered,omitempty"`
+	TimeoutTimestamp string `json:"timeout_timestamp,omitempty"`
+
+	// when the tx was pushed, RFC3339
+	Pushed string `json:"pushed,omitempty"`
 }
 
 func main() {
@@ -60,16 +86,30 @@ func cmdDelete(cobraCmd *cobra.Command, args []string) error {
 	txIndex := flagTxIndex
 	txDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", txIndex))
 
+	sess := awsSession(conf.AWS)
+
+	// no tx may be pushed or broadcast while the queue is renumbered
+	release, err := acquireLock(sess, conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	err = deleteAllFilesInPath(txDir, conf)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// renumber the remaining txs, so the deleted one doesn't leave a gap
+	return reindexQueue(sess, conf, chainName, keyName)
 }
 
 // Generates a [binary] `tx distribution withdraw-all-rewards` transaction
 func cmdWithdraw(cmd *cobra.Command, args []string) error {
+	return withdrawTx(cmd, args, flagPushOptions())
+}
+
+func withdrawTx(cmd *cobra.Command, args []string, opts pushOptions) error {
 	chainName := args[0]
 	keyName := args[1]
 
@@ -87,7 +127,7 @@ func cmdWithdraw(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -99,7 +139,7 @@ func cmdWithdraw(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -118,19 +158,19 @@ func cmdWithdraw(cmd *cobra.Command, args []string) error {
 	}
 
 	execCmd := exec.Command(binary, cmdArgs...)
-	fmt.Println(execCmd)
+	fmt.Fprintln(opts.out, execCmd)
 	unsignedBytes, err := execCmd.CombinedOutput()
 	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(execCmd)
-		fmt.Println(string(unsignedBytes))
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, "call failed")
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, execCmd)
+		fmt.Fprintln(opts.out, string(unsignedBytes))
 		return err
 	}
-	fmt.Println(string(unsignedBytes))
+	fmt.Fprintln(opts.out, string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, opts)
 }
 
 // Generates a [binary] `tx staking delegate` transaction
@@ -168,7 +208,7 @@ func cmdDelegate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -186,7 +226,7 @@ func cmdDelegate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -229,10 +269,14 @@ func cmdDelegate(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println(string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, flagPushOptions())
 }
 
 func cmdClaimValidator(cmd *cobra.Command, args []string) error {
+	return claimValidatorTx(cmd, args, flagPushOptions())
+}
+
+func claimValidatorTx(cmd *cobra.Command, args []string, opts pushOptions) error {
 	chainName := args[0]
 	keyName := args[1]
 	valAddress := args[2]
@@ -251,7 +295,7 @@ func cmdClaimValidator(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -263,7 +307,7 @@ func cmdClaimValidator(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -284,49 +328,57 @@ func cmdClaimValidator(cmd *cobra.Command, args []string) error {
 	}
 
 	// Append keyring if specified in the config
-	if conf.KeyringBackend != "" {
-		cmdArgs = append(cmdArgs, "--keyring-backend", conf.KeyringBackend)
+	if backend := conf.GetKeyringBackend(chain); backend != "" {
+		cmdArgs = append(cmdArgs, "--keyring-backend", backend)
+	}
+	home, err := chainHome(chain)
+	if err != nil {
+		return err
+	}
+	if home != "" {
+		cmdArgs = append(cmdArgs, "--home", home)
 	}
 
 	execCmd := exec.Command(binary, cmdArgs...)
-	fmt.Println(execCmd)
+	fmt.Fprintln(opts.out, execCmd)
 	unsignedBytes, err := execCmd.CombinedOutput()
 	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(execCmd)
-		fmt.Println(string(unsignedBytes))
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, "call failed")
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, execCmd)
+		fmt.Fprintln(opts.out, string(unsignedBytes))
 		return err
 	}
-	fmt.Println(string(unsignedBytes))
+	fmt.Fprintln(opts.out, string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, opts)
+}
+
+// the message types authz grants can be given for, by their name in the tx authz commands.
+// Only support the messages we need for now (withdraw, delegate, commission, vote)
+var authzMsgTypes = map[string]string{
+	"withdraw":   "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward",
+	"delegate":   "/cosmos.staking.v1beta1.MsgDelegate",
+	"commission": "/cosmos.distribution.v1beta1.MsgWithdrawValidatorCommission",
+	"vote":       "/cosmos.gov.v1beta1.MsgVote",
+	"unbond":     "/cosmos.staking.v1beta1.MsgUndelegate",
+	"redelegate": "/cosmos.staking.v1beta1.MsgBeginRedelegate",
 }
 
 func cmdGrantAuthz(cmd *cobra.Command, args []string) error {
+	return grantAuthzTx(cmd, args, flagPushOptions())
+}
+
+func grantAuthzTx(cmd *cobra.Command, args []string, opts pushOptions) error {
 	chainName := args[0]
 	keyName := args[1]
 	grantee := args[2]
 
 	msgType := args[3]
 	// Parse message-type parameter and generate proper tx msg-type
-	// Only support the messages we need for now (withdraw, delegate, commission, vote)
-	var cosmosMsg string
-	switch msgType {
-	case "withdraw":
-		cosmosMsg = "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward"
-	case "delegate":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgDelegate"
-	case "commission":
-		cosmosMsg = "/cosmos.distribution.v1beta1.MsgWithdrawValidatorCommission"
-	case "vote":
-		cosmosMsg = "/cosmos.gov.v1beta1.MsgVote"
-	case "unbond":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgUndelegate"
-	case "redelegate":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgBeginRedelegate"
-	default:
+	cosmosMsg, found := authzMsgTypes[msgType]
+	if !found {
 		return fmt.Errorf("message type %s not supported", msgType)
 	}
 
@@ -353,7 +405,7 @@ func cmdGrantAuthz(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -365,7 +417,7 @@ func cmdGrantAuthz(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -386,19 +438,19 @@ func cmdGrantAuthz(cmd *cobra.Command, args []string) error {
 	}
 
 	execCmd := exec.Command(binary, cmdArgs...)
-	fmt.Println(execCmd)
+	fmt.Fprintln(opts.out, execCmd)
 	unsignedBytes, err := execCmd.CombinedOutput()
 	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(execCmd)
-		fmt.Println(string(unsignedBytes))
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, "call failed")
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, execCmd)
+		fmt.Fprintln(opts.out, string(unsignedBytes))
 		return err
 	}
-	fmt.Println(string(unsignedBytes))
+	fmt.Fprintln(opts.out, string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, opts)
 }
 
 func cmdRevokeAuthz(cmd *cobra.Command, args []string) error {
@@ -408,22 +460,8 @@ func cmdRevokeAuthz(cmd *cobra.Command, args []string) error {
 
 	msgType := args[3]
 	// Parse message-type parameter and generate proper tx msg-type
-	// Only support the messages we need for now (withdraw, delegate, commission, vote)
-	var cosmosMsg string
-	switch msgType {
-	case "withdraw":
-		cosmosMsg = "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward"
-	case "delegate":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgDelegate"
-	case "commission":
-		cosmosMsg = "/cosmos.distribution.v1beta1.MsgWithdrawValidatorCommission"
-	case "vote":
-		cosmosMsg = "/cosmos.gov.v1beta1.MsgVote"
-	case "unbond":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgUndelegate"
-	case "redelegate":
-		cosmosMsg = "/cosmos.staking.v1beta1.MsgBeginRedelegate"
-	default:
+	cosmosMsg, found := authzMsgTypes[msgType]
+	if !found {
 		return fmt.Errorf("message type %s not supported", msgType)
 	}
 
@@ -441,7 +479,7 @@ func cmdRevokeAuthz(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -453,7 +491,7 @@ func cmdRevokeAuthz(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -484,10 +522,14 @@ func cmdRevokeAuthz(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println(string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, flagPushOptions())
 }
 
 func cmdVote(cmd *cobra.Command, args []string) error {
+	return voteTx(cmd, args, flagPushOptions())
+}
+
+func voteTx(cmd *cobra.Command, args []string, opts pushOptions) error {
 	chainName := args[0]
 	keyName := args[1]
 	propID := args[2]
@@ -507,7 +549,7 @@ func cmdVote(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	nodeAddress := chain.Node
+	nodeAddress := chain.RPC
 	if flagNode != "" {
 		nodeAddress = flagNode
 	}
@@ -519,7 +561,7 @@ func cmdVote(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get fees
-	fees, err := getFeesParameter(cmd)
+	fees, err := getFeesParameter(cmd, conf, chain)
 	if err != nil {
 		return err
 	}
@@ -538,19 +580,19 @@ func cmdVote(cmd *cobra.Command, args []string) error {
 	}
 
 	execCmd := exec.Command(binary, cmdArgs...)
-	fmt.Println(execCmd)
+	fmt.Fprintln(opts.out, execCmd)
 	unsignedBytes, err := execCmd.CombinedOutput()
 	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(execCmd)
-		fmt.Println(string(unsignedBytes))
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, "call failed")
+		fmt.Fprintln(opts.out, "-----------------------------------------------------------------")
+		fmt.Fprintln(opts.out, execCmd)
+		fmt.Fprintln(opts.out, string(unsignedBytes))
 		return err
 	}
-	fmt.Println(string(unsignedBytes))
+	fmt.Fprintln(opts.out, string(unsignedBytes))
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, opts)
 }
 
 func cmdPush(cmd *cobra.Command, args []string) error {
@@ -579,12 +621,12 @@ func cmdPush(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return pushTx(chainName, keyName, unsignedBytes, cmd)
+	return pushTx(chainName, keyName, unsignedBytes, cmd, flagPushOptions())
 }
 
-func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Command) error {
+func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Command, opts pushOptions) error {
 
-	if flagForce && flagAdditional {
+	if flagForce && opts.additional {
 		return fmt.Errorf("cannot specify both --force and --additional")
 	}
 
@@ -607,20 +649,23 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 	// either from a node and/or from CLI
 	//------------------------------------
 
-	nodeAddress := chain.Node
+	if flagREST != "" {
+		chain.REST = flagREST
+	}
 	if flagNode != "" {
-		nodeAddress = flagNode
+		chain.RPC = flagNode
 	}
+	client := newChainClient(chain)
 
 	isAccSet := cmd.Flags().Changed("account")
 	isSeqSet := cmd.Flags().Changed("sequence")
 
-	// if both account and sequence are not set, the node must be set in the config or CLI
+	// if both account and sequence are not set, they are queried from the rest endpoint,
+	// or with the binary through the rpc endpoint
 	noAccOrSeq := !(isAccSet && isSeqSet)
-	noNode := nodeAddress == ""
-	if noAccOrSeq && noNode {
-		fmt.Println("if the --account and --sequence are not provided, a node must be specified in the config or with --node")
-		return nil
+	noRest := chain.REST == ""
+	if noAccOrSeq && noRest && chain.RPC == "" {
+		return fmt.Errorf("if --account and --sequence are not provided, a rest or rpc endpoint must be specified in the config or with --rest or --node")
 	}
 
 	var (
@@ -628,30 +673,38 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 		sequenceNum int
 	)
 
-	// if both account and sequence are not set, get them from the node
-	if noAccOrSeq {
-		var err2 error
-		httpClient := NewHttpClient()
+	// there's no good default: the signers need as long as possible, but chains refuse timeouts past their max
+	if flagUnordered && flagTimeout <= 0 {
+		return fmt.Errorf("--timeout is required for unordered txs, e.g. 10m, and can't be more than the max timeout of the chain (10m by default)")
+	}
 
-		nodeInfo, err2 := GetNodeInfo(chain, httpClient)
-		if err2 != nil {
-			return err2
+	// the cosmos-sdk version of the chain is only served by the rest endpoint
+	if flagUnordered && noRest {
+		fmt.Printf("WARNING: no rest endpoint for %s, cannot check it runs cosmos-sdk v0.53 or greater for unordered txs\n", chainName)
+	} else if flagUnordered {
+		nodeInfo, err := client.NodeInfo()
+		if err != nil {
+			return err
 		}
-
-		sdkVersion, err2 := parseSdkVersionFromJson(nodeInfo)
-		if err2 != nil {
-			return err2
+		sdkVersion, err := parseSdkVersionFromJson(nodeInfo)
+		if err != nil {
+			return err
+		}
+		if !isSDK053OrGreater(sdkVersion) {
+			return fmt.Errorf("unordered txs need cosmos-sdk v0.53 or greater, %s runs %s", chainName, sdkVersion)
 		}
+	}
 
-		binary := chain.Binary
-		address, err2 := bech32ify(key.Address, chain.Prefix)
-		if err2 != nil {
-			return err2
+	// if both account and sequence are not set, get them from the node
+	if noAccOrSeq {
+		address, err := bech32ify(key.Address, chain.Prefix)
+		if err != nil {
+			return err
 		}
 
-		accountNum, sequenceNum, err2 = getAccSeq(binary, address, nodeAddress, sdkVersion)
-		if err2 != nil {
-			return err2
+		accountNum, sequenceNum, err = client.AccSeq(address)
+		if err != nil {
+			return fmt.Errorf("cannot query the account of %s on %s: %s", keyName, chainName, err)
 		}
 	}
 
@@ -663,10 +716,28 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 		sequenceNum = flagSequence
 	}
 
+	// unordered txs are replay protected by their timeout instead of a sequence
+	timeoutTimestamp := ""
+	if flagUnordered {
+		sequenceNum = 0
+		timeoutTimestamp = time.Now().Add(flagTimeout).UTC().Format(time.RFC3339)
+		unsignedTxBytes, err = setUnordered(unsignedTxBytes, timeoutTimestamp)
+		if err != nil {
+			return err
+		}
+	}
+
 	txDir := filepath.Join(chainName, keyName)
 
 	sess := awsSession(conf.AWS)
 
+	// lock the queue, so concurrent pushes can't allocate the same index or overwrite each other
+	release, err := acquireLock(sess, conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// check if a file already exists
 	files, err := awsListFilesInDir(sess, conf.AWS, chainName, keyName)
 	if err != nil {
@@ -674,9 +745,9 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 	}
 
 	// if there is already files there, and we don't specify -f or -x, return
-	if len(files) > 0 && !(flagForce || flagAdditional) {
+	if len(files) > 0 && !(flagForce || opts.additional) {
 		return fmt.Errorf("files already exist for %s/%s. Use -f to force overwrite or -x to add additional txs", chainName, keyName)
-	} else if len(files) == 0 && (flagForce || flagAdditional) {
+	} else if len(files) == 0 && (flagForce || opts.additional) {
 		return fmt.Errorf("path %s/%s is empty, Cannot specify --force or --additional", chainName, keyName)
 	}
 
@@ -690,7 +761,7 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 
 	// if we're pushing additional files, figure out what the highest number is and increment,
 	// and add that to the sequence number
-	if flagAdditional {
+	if opts.additional {
 		// figure out what highest number in the files is
 		// files should be either "filename.json" or "n/filename.json"
 		for _, fullPathFile := range files {
@@ -711,8 +782,13 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 
 		N += 1
 
-		if !isSeqSet {
-			sequenceNum += N
+		// pending unordered txs don't use up a sequence
+		if !isSeqSet && !flagUnordered {
+			unordered, err := countUnordered(sess, conf, chainName, keyName)
+			if err != nil {
+				return err
+			}
+			sequenceNum += N - unordered
 		}
 	}
 	txDir = filepath.Join(txDir, fmt.Sprintf("%d", N))
@@ -725,10 +801,13 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 
 	// create and marshal the sign data
 	signData := SignData{
-		Account:     accountNum,
-		Sequence:    sequenceNum,
-		ChainID:     chain.ID,
-		Description: flagDescription,
+		Account:          accountNum,
+		Sequence:         sequenceNum,
+		ChainID:          chain.ID,
+		Description:      opts.description,
+		Unordered:        flagUnordered,
+		TimeoutTimestamp: timeoutTimestamp,
+		Pushed:           time.Now().UTC().Format(time.RFC3339),
 	}
 	signDataBytes, err := json.Marshal(signData)
 	if err != nil {
@@ -745,7 +824,12 @@ func pushTx(chainName, keyName string, unsignedTxBytes []byte, cmd *cobra.Comman
 		return err
 	}
 
-	fmt.Printf("pushed %s and %s files to %s\n", unsignedJSON, signedJSON, txDir)
+	// upload the manifest, so signers can check the files weren't changed since
+	if err := writeManifest(sess, conf, txDir, unsignedTxBytes, signDataBytes); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.out, "pushed %s and %s files to %s\n", unsignedJSON, signedJSON, txDir)
 	return nil
 }
 
@@ -757,17 +841,26 @@ func cmdSign(cobraCmd *cobra.Command, args []string) error {
 		upload the signature to the right bucket
 	*/
 
-	chainName := args[0]
-	keyName := args[1]
-
-	from := flagFrom
-	txIndex := flagTxIndex
-
 	conf, err := loadConfig(flagConfigPath)
 	if err != nil {
 		return err
 	}
 
+	sess := awsSession(conf.AWS)
+
+	if flagAll {
+		if len(args) != 0 {
+			return fmt.Errorf("cannot specify a chain and key with --all")
+		}
+		return signAll(sess, conf)
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("must specify args: <chain name> <key name>, or --all")
+	}
+
+	chainName := args[0]
+	keyName := args[1]
+
 	chain, found := conf.GetChain(chainName)
 	if !found {
 		return fmt.Errorf("chain %s not found in config", chainName)
@@ -778,56 +871,52 @@ func cmdSign(cobraCmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
-	txDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", txIndex))
+	signer, err := localSigner(conf, chain, key)
+	if err != nil {
+		return err
+	}
 
-	sess := awsSession(conf.AWS)
-	downloader := s3manager.NewDownloader(sess)
+	return signTx(sess, conf, chain, key, flagTxIndex, signer)
+}
 
-	// Make a file for the unsigned.json, download it
+// sign the tx at txIndex of a chain/key pair with our local signer, and upload the signature
+func signTx(sess *session.Session, conf *Config, chain Chain, key Key, txIndex int, signer Signer) error {
+	txDir := filepath.Join(chain.Name, key.Name, fmt.Sprintf("%d", txIndex))
 
-	unsignedFile, err := os.CreateTemp("", "temp")
+	// Download the unsigned.json and sign data
+
+	unsignedBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, unsignedJSON)
 	if err != nil {
 		return err
 	}
-	defer func(name string) {
-		_ = os.Remove(name)
-	}(unsignedFile.Name())
 
-	unsignedPath := filepath.Join(txDir, unsignedJSON)
-	numBytes, err := downloader.Download(unsignedFile,
-		&s3.GetObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    aws.String(unsignedPath),
-		})
+	signDataBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, signDataJSON)
 	if err != nil {
 		return err
 	}
-	_ = numBytes
 
-	// Make a file for sign data, download it
+	if err := verifyManifest(sess, conf, txDir, unsignedBytes, signDataBytes); err != nil {
+		return err
+	}
+
+	// Make a file for the unsigned.json to pass to the binary
 
-	signDataFile, err := os.CreateTemp("", "temp")
+	unsignedFile, err := os.CreateTemp("", "temp")
 	if err != nil {
 		return err
 	}
 	defer func(name string) {
 		_ = os.Remove(name)
-	}(signDataFile.Name())
+	}(unsignedFile.Name())
 
-	signDataPath := filepath.Join(txDir, signDataJSON)
-	numBytes, err = downloader.Download(signDataFile,
-		&s3.GetObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    aws.String(signDataPath),
-		})
-	if err != nil {
+	if _, err := unsignedFile.Write(unsignedBytes); err != nil {
+		return err
+	}
+	if err := unsignedFile.Close(); err != nil {
 		return err
 	}
-	_ = numBytes
 
 	// TODO: pretty print and confirm the unsigned tx
-	unsignedBytes, _ := io.ReadAll(unsignedFile)
-	signDataBytes, _ := io.ReadAll(signDataFile)
 	fmt.Println("You are signing the following tx:")
 	fmt.Println(string(unsignedBytes))
 	fmt.Println("With the following sign data:")
@@ -847,18 +936,22 @@ func cmdSign(cobraCmd *cobra.Command, args []string) error {
 	seqNum := fmt.Sprintf("%d", signData.Sequence)
 	chainID := signData.ChainID
 	unsignedFileName := unsignedFile.Name()
-	backend := conf.KeyringBackend
+	home, backend, err := signerKeystore(conf, chain, signer)
+	if err != nil {
+		return err
+	}
 	user := conf.User
 
 	// gaiad tx sign unsigned.json --multisig <address> --from <from> --account-number <acc> --sequence <seq> --chain-id <id> --offline
-	cmdArgs := []string{"tx", "sign", unsignedFileName, "--multisig", address, "--from", from,
+	cmdArgs := []string{"tx", "sign", unsignedFileName, "--multisig", address, "--from", signer.Name,
 		"--account-number", accNum, "--sequence", seqNum, "--chain-id", chainID,
 		"--sign-mode", "amino-json",
 		"--offline",
 	}
 	cmdArgs = append(cmdArgs, "--keyring-backend", backend)
-	if flagHomePath != "" {
-		cmdArgs = append(cmdArgs, "--home", flagHomePath)
+
+	if home != "" {
+		cmdArgs = append(cmdArgs, "--home", home)
 	}
 	cmd := exec.Command(binary, cmdArgs...)
 	b, err := cmd.CombinedOutput()
@@ -900,59 +993,64 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key %s not found in config", keyName)
 	}
 
+	// the final code of the tx is waited for on the rest endpoint, or else the rpc one
+	if flagREST != "" {
+		chain.REST = flagREST
+	}
+	if flagNode != "" {
+		chain.RPC = flagNode
+	}
+
 	txIndex := flagTxIndex
 	txDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", txIndex))
 
 	sess := awsSession(conf.AWS)
-	svc := s3.New(sess)
 
-	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.AWS.Bucket)})
+	// lock the queue from checking the order of the txs to archiving this one and reindexing the others,
+	// so no other tx is pushed, broadcast or reindexed in the meantime
+	release, err := acquireLockFor(sess, conf, chainName, keyName, broadcastLockLease)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	//--------------------------------
-	// txIndex specified must be smallest index for this chainName/keyName pair,
-	// otherwise error
+	// txIndex specified must be smallest index of the ordered txs for this chainName/keyName pair,
+	// otherwise error. Unordered txs can be broadcast in any order, until they time out
 
-	files, err := awsListFilesInDir(sess, conf.AWS, chainName, keyName)
+	queued, err := fetchSignData(sess, conf, txDir)
 	if err != nil {
 		return err
 	}
-
-	// see if any indices are smaller than txIndex, and if so, quit
-	for _, fullPathFile := range files {
-		dirPrefix := filepath.Join(chainName, keyName)
-		f := strings.TrimPrefix(fullPathFile, dirPrefix+"/")
-		spl := strings.Split(f, "/")
-		if len(spl) == 1 {
-			continue
+	if queued.Unordered {
+		if err := checkTimeout(queued); err != nil {
+			return err
 		}
-		nString := spl[0]
-		n, err := strconv.Atoi(nString)
+	} else {
+		queue, err := listQueue(sess, conf, chainName, keyName)
 		if err != nil {
-			return fmt.Errorf("failed to read number after %s in path %s", txDir, fullPathFile)
+			return err
 		}
-		if n < txIndex {
-			return fmt.Errorf("found index %d smaller than specified txIndex %d. txs must be broadcast in order", n, txIndex)
+
+		// see if any ordered txs have a smaller index than txIndex, and if so, quit
+		for _, tx := range queue {
+			if tx.Index >= txIndex {
+				break
+			}
+			pending, err := fetchSignData(sess, conf, filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index)))
+			if err != nil {
+				return err
+			}
+			if !pending.Unordered {
+				return fmt.Errorf("found index %d smaller than specified txIndex %d. txs must be broadcast in order", tx.Index, txIndex)
+			}
 		}
 	}
 	//--------------------------------
 
-	fileNames := []string{}
-	for _, item := range resp.Contents {
-		itemKey := *item.Key
-		if strings.HasPrefix(itemKey, txDir) && !strings.HasSuffix(itemKey, "/") {
-			base := filepath.Base(itemKey)
-
-			// sanity check
-			if len(base) == 0 {
-				return fmt.Errorf("%s had empty base", itemKey)
-			}
-
-			fileNames = append(fileNames, base)
-		}
+	fileNames, err := listFilesInPath(sess, conf, txDir)
+	if err != nil {
+		return err
 	}
 
 	for _, f := range fileNames {
@@ -964,18 +1062,14 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// get the names of the signatures (everything except unsigned.json and signdata.json)
-	sigFileNames := []string{}
-	for _, f := range fileNames {
-		if f == unsignedJSON || f == signDataJSON {
-			continue
-		}
-		sigFileNames = append(sigFileNames, f)
-	}
+	// get the names of the signatures (everything except unsigned.json, signdata.json and manifest.json)
+	sigFileNames := signatureFiles(fileNames)
 
-	// TODO: add this to the key config so its not hardcoded to 2.
-	// can default to 2 tho
-	threshold := 2
+	multisig, err := lookupMultisig(conf, chain, key)
+	if err != nil {
+		fmt.Printf("WARNING: cannot read the multisig of %s, using a threshold of %d: %s\n", key.Name, key.GetThreshold(), err)
+	}
+	threshold := multisigThreshold(key, multisig)
 	if len(sigFileNames) < threshold {
 		return fmt.Errorf("Insufficient signatures for broadcast. Requires %d, got %d", threshold, len(sigFileNames))
 	}
@@ -985,18 +1079,38 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	unsignedBytes, err := os.ReadFile(unsignedJSON)
+	if err != nil {
+		return err
+	}
+	if err := verifyManifest(sess, conf, txDir, unsignedBytes, signDataBytes); err != nil {
+		return err
+	}
 	var signData SignData
 	if err := json.Unmarshal(signDataBytes, &signData); err != nil {
 		return err
 	}
 
+	// make sure each signature was made by the member it is named after
+	sigBytes := map[string][]byte{}
+	for _, f := range sigFileNames {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		sigBytes[f] = b
+	}
+	if err := checkSignatureAuthors(sess, conf, txDir, sigFileNames, sigBytes); err != nil {
+		return err
+	}
+
 	// setup for the `tx multisign` command
 	binary := chain.Binary
 	accNum := fmt.Sprintf("%d", signData.Account)
 	seqNum := fmt.Sprintf("%d", signData.Sequence)
 	chainID := signData.ChainID
 	unsignedFileName := unsignedJSON
-	backend := conf.KeyringBackend
+	backend := conf.GetKeyringBackend(chain)
 
 	// Check if the local multisig key name was passed as a parameter,
 	// if not then check the config file, if also not in the file
@@ -1019,15 +1133,16 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 	cmdArgs = append(cmdArgs, "--account-number", accNum, "--sequence", seqNum, "--chain-id", chainID, "--offline")
 	cmdArgs = append(cmdArgs, "--keyring-backend", backend) // sigh
 
-	nodeAddress := chain.Node
-	if flagNode != "" {
-		nodeAddress = flagNode
-	}
+	nodeAddress := chain.RPC
 	if nodeAddress != "" {
 		cmdArgs = append(cmdArgs, "--node", nodeAddress)
 	}
-	if flagHomePath != "" {
-		cmdArgs = append(cmdArgs, "--home", flagHomePath)
+	home, err := chainHome(chain)
+	if err != nil {
+		return err
+	}
+	if home != "" {
+		cmdArgs = append(cmdArgs, "--home", home)
 	}
 
 	cmd := exec.Command(binary, cmdArgs...)
@@ -1048,9 +1163,7 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 	}
 
 	// broadcast tx
-	// TODO: use --broadcast-mode block ?
-	// 	  otherwise the tx might still fail when it gets executed but we will delete it
-	cmdArgs = []string{"tx", "broadcast", signedJSON, "--node", nodeAddress}
+	cmdArgs = []string{"tx", "broadcast", signedJSON, "--node", nodeAddress, "--output", "json"}
 	cmd = exec.Command(binary, cmdArgs...)
 	b, err = cmd.CombinedOutput()
 	if err != nil {
@@ -1064,29 +1177,47 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 	fmt.Println(cmd)
 	fmt.Println(string(b))
 
-	code, hash, err := parseTxResult(b)
+	result, err := parseBroadcastResult(b)
 	if err != nil {
 		return err
 	}
 
-	// TODO: write the result in a log file
-	_, _ = code, hash
+	// a tx rejected by the checks of the node was never included, so it stays in the queue
+	if result.Code != 0 {
+		return fmt.Errorf("tx %s was rejected by the node with code %d, it stays queued in %s: %s", result.TxHash, result.Code, txDir, result.RawLog)
+	}
 
-	// cleanup txDir in the bucket by deleting everything
-	for _, f := range fileNames {
-		awsString := aws.String(filepath.Join(txDir, f))
-		_, err = svc.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    awsString,
-		})
-		if err != nil {
-			return err
-		}
+	// the tx only passed the checks of the node, it can still fail when executed,
+	// so wait for it to be included in a block to get its final code
+	if included, err := newChainClient(chain).WaitTx(result.TxHash, txInclusionTimeout); err != nil {
+		fmt.Printf("WARNING: cannot check tx %s was executed: %s\n", result.TxHash, err)
+	} else {
+		fmt.Printf("tx %s included at height %s with code %d\n", included.TxHash, included.Height, included.Code)
+		result = included
+	}
+	code, hash := result.Code, result.TxHash
+
+	// keep everything that was signed, and by whom, in the archive
+	// and cleanup txDir in the bucket
+	record := ArchiveRecord{
+		Chain:       chainName,
+		Key:         keyName,
+		Index:       txIndex,
+		Action:      "broadcast",
+		TxHash:      hash,
+		Code:        code,
+		Description: signData.Description,
+	}
+	if err := archiveTx(sess, conf, txDir, fileNames, record); err != nil {
+		return err
+	}
 
-		err = svc.WaitUntilObjectNotExists(&s3.HeadObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    awsString,
-		})
+	// renumber the remaining txs, so the next one to broadcast is index 0 again
+	if err := reindexQueue(sess, conf, chainName, keyName); err != nil {
+		return err
+	}
+	if code != 0 {
+		fmt.Printf("WARNING: the tx failed with code %d so its sequence wasn't used, the remaining txs may need new sequences, see `multisig check %s %s`\n", code, chainName, keyName)
 	}
 
 	// Remove all downloaded files and the signed.json
@@ -1103,53 +1234,20 @@ func cmdBroadcast(cobraCmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// TODO can we get a result from the tx without having to parse the tx response? use the API instead of CLI
-// Need to parse out the return code and the tx hash
-func parseTxResult(txResultBytes []byte) (int, string, error) {
-	spl := strings.Split(string(txResultBytes), "\n")
-	var (
-		code      int
-		codeFound bool
-		txhash    string
-		err       error
-	)
-	for _, s := range spl {
-		if strings.Contains(s, "code:") {
-			c := strings.TrimPrefix(s, "code: ")
-			code, err = strconv.Atoi(c)
-			if err != nil {
-				return 0, "", fmt.Errorf("code in tx response is not an integer")
-			}
-			codeFound = true
-
-		} else if strings.Contains(s, "txhash:") {
-			txhash = strings.TrimPrefix(s, "txhash: ")
-		}
+// parse the json output of `tx broadcast`, skipping anything the binary printed before it
+func parseBroadcastResult(b []byte) (TxResult, error) {
+	var result TxResult
+	i := bytes.IndexByte(b, '{')
+	if i < 0 {
+		return result, fmt.Errorf("no json in the broadcast response")
 	}
-
-	if !codeFound {
-		return 0, "", fmt.Errorf("couldn't find code in tx response")
-	} else if txhash == "" {
-		return 0, "", fmt.Errorf("couldn't find txhash in tx response")
+	if err := json.NewDecoder(bytes.NewReader(b[i:])).Decode(&result); err != nil {
+		return result, fmt.Errorf("cannot parse the broadcast response: %s", err)
 	}
-	return code, txhash, nil
-}
-
-// Parse out the account and sequence number
-// Return: accountNumber, sequenceNumber, error
-func parseAccountQueryResponse(queryResponseBytes []byte, sdkVersion string) (int, int, error) {
-	// SDK 0.50+ uses a different response format with wrapped account info
-	if isSDK050OrGreater(sdkVersion) {
-		acct, seq, err := parseAcctByType50(queryResponseBytes)
-		if err != nil {
-			// Some chains report SDK >= 0.50 but still return the old response format.
-			// Fall back to the pre-0.50 parser.
-			return parseAcctByType(queryResponseBytes)
-		}
-		return acct, seq, nil
-	} else {
-		return parseAcctByType(queryResponseBytes)
+	if result.TxHash == "" {
+		return result, fmt.Errorf("couldn't find txhash in the broadcast response")
 	}
+	return result, nil
 }
 
 func convertAcctDetails(acctSeq string, acctNum string) (int, int, error) {
@@ -1164,132 +1262,46 @@ func convertAcctDetails(acctSeq string, acctNum string) (int, int, error) {
 	return accInt, seqInt, nil
 }
 
-func parseAcctByType50(respBytes []byte) (int, int, error) {
-	var acctType AcctType50
-	err := json.Unmarshal(respBytes, &acctType)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to unmarshal account type: %s", err)
-	}
-
-	// Some SDK v0.50+ chains use proto-JSON encoding with @type instead of Amino type/value.
-	if acctType.Account.Type == "" {
-		return parseAcctByType50Proto(respBytes)
-	}
-
+// parse the account and sequence numbers of an account of the given type, as returned by unwrapAccount
+func parseAcctByType(acctType string, respBytes []byte) (int, int, error) {
+	var err error
 	switch {
-	case strings.Contains(acctType.Account.Type, "BaseAccount"):
-		var ba BaseAccount50
-		err = json.Unmarshal(respBytes, &ba)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal base account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(ba.Account.Value.Sequence, ba.Account.Value.AccountNumber)
-	case strings.Contains(acctType.Account.Type, "PeriodicVestingAccount"):
-		var pva PeriodicVestingAccount50
-		err = json.Unmarshal(respBytes, &pva)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal periodic vesting account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(pva.Account.Value.BaseVestingAccount.BaseAccount.Sequence, pva.Account.Value.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Account.Type, "ContinuousVestingAccount"):
-		var cva ContinuousVestingAccount50
-		err = json.Unmarshal(respBytes, &cva)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal continuous vesting account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(cva.Account.Value.BaseVestingAccount.BaseAccount.Sequence, cva.Account.Value.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Account.Type, "DelayedVestingAccount"):
-		var dva DelayedVestingAccount50
-		err = json.Unmarshal(respBytes, &dva)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal delayed vesting account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(dva.Account.Value.BaseVestingAccount.BaseAccount.Sequence, dva.Account.Value.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Account.Type, "StridePeriodicVestingAccount"):
-		var spva StridePeriodicVestingAccount50
-		err = json.Unmarshal(respBytes, &spva)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal stride periodic vesting account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(spva.Account.Value.BaseVestingAccount.BaseAccount.Sequence, spva.Account.Value.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Account.Type, "EthAccount"):
-		var ea EthAccount50
-		err = json.Unmarshal(respBytes, &ea)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal eth account (SDK v0.50+): %s", err)
-		}
-		return convertAcctDetails(ea.Account.Value.BaseAccount.Sequence, ea.Account.Value.BaseAccount.AccountNumber)
-	default:
-		return 0, 0, fmt.Errorf("unknown account type (SDK v0.50+): %s", acctType.Account.Type)
-	}
-}
-
-// parseAcctByType50Proto handles SDK v0.50+ chains that return proto-JSON
-// encoding (using @type with fields directly in the account object, no value wrapper).
-func parseAcctByType50Proto(respBytes []byte) (int, int, error) {
-	var acctType AcctType50Proto
-	err := json.Unmarshal(respBytes, &acctType)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to unmarshal proto account type: %s", err)
-	}
-
-	switch {
-	case strings.Contains(acctType.Account.Type, "BaseAccount"):
-		var ba BaseAccount50Proto
-		err = json.Unmarshal(respBytes, &ba)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal base account (proto): %s", err)
-		}
-		return convertAcctDetails(ba.Account.Sequence, ba.Account.AccountNumber)
-	default:
-		return 0, 0, fmt.Errorf("unknown account type (SDK v0.50+ proto): %s", acctType.Account.Type)
-	}
-}
-
-func parseAcctByType(respBytes []byte) (int, int, error) {
-	var acctType AccountType
-	err := json.Unmarshal(respBytes, &acctType)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to unmarshal account type: %s", err)
-	}
-
-	switch {
-	case strings.Contains(acctType.Type, "BaseAccount"):
+	case strings.Contains(acctType, "BaseAccount"):
 		var ba BaseAccount
 		err = json.Unmarshal(respBytes, &ba)
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to unmarshal base account: %s", err)
 		}
 		return convertAcctDetails(ba.Sequence, ba.AccountNumber)
-	case strings.Contains(acctType.Type, "PeriodicVestingAccount"):
+	case strings.Contains(acctType, "StridePeriodicVestingAccount"):
+		var spva StridePeriodicVestingAccount
+		err = json.Unmarshal(respBytes, &spva)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to unmarshal stride periodic vesting account: %s", err)
+		}
+		return convertAcctDetails(spva.BaseVestingAccount.BaseAccount.Sequence, spva.BaseVestingAccount.BaseAccount.AccountNumber)
+	case strings.Contains(acctType, "PeriodicVestingAccount"):
 		var pva PeriodicVestingAccount
 		err = json.Unmarshal(respBytes, &pva)
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to unmarshal periodic vesting account: %s", err)
 		}
 		return convertAcctDetails(pva.BaseVestingAccount.BaseAccount.Sequence, pva.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Type, "ContinuousVestingAccount"):
+	case strings.Contains(acctType, "ContinuousVestingAccount"):
 		var cva ContinuousVestingAccount
 		err = json.Unmarshal(respBytes, &cva)
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to unmarshal continuous vesting account: %s", err)
 		}
 		return convertAcctDetails(cva.BaseVestingAccount.BaseAccount.Sequence, cva.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Type, "DelayedVestingAccount"):
+	case strings.Contains(acctType, "DelayedVestingAccount"):
 		var dva DelayedVestingAccount
 		err = json.Unmarshal(respBytes, &dva)
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to unmarshal delayed vesting account: %s", err)
 		}
 		return convertAcctDetails(dva.BaseVestingAccount.BaseAccount.Sequence, dva.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Type, "StridePeriodicVestingAccount"):
-		var spva StridePeriodicVestingAccount
-		err = json.Unmarshal(respBytes, &spva)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to unmarshal stride periodic vesting account: %s", err)
-		}
-		return convertAcctDetails(spva.BaseVestingAccount.BaseAccount.Sequence, spva.BaseVestingAccount.BaseAccount.AccountNumber)
-	case strings.Contains(acctType.Type, "EthAccount"):
+	case strings.Contains(acctType, "EthAccount"):
 		var ea EthAccount
 		err = json.Unmarshal(respBytes, &ea)
 		if err != nil {
@@ -1297,65 +1309,15 @@ func parseAcctByType(respBytes []byte) (int, int, error) {
 		}
 		return convertAcctDetails(ea.BaseAccount.Sequence, ea.BaseAccount.AccountNumber)
 	}
-	return 0, 0, fmt.Errorf("unknown account type: %s", acctType.Type)
-}
-
-// Return: accountNumber, sequenceNumber, error
-func getAccSeq(binary, addr, node string, sdkVersion string) (int, int, error) {
-	var cmdArgs []string
-
-	// SDK version command mappings:
-	// SDK < 0.50: query account
-	// SDK >= 0.50: query auth account
-	if isSDK050OrGreater(sdkVersion) {
-		cmdArgs = []string{"query", "auth", "account", addr, "--output", "json", "--node", node}
-	} else {
-		cmdArgs = []string{"query", "--node", node, "account", addr, "--output", "json"}
-	}
-
-	cmd := exec.Command(binary, cmdArgs...)
-	b, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(cmd)
-		fmt.Println(string(b))
-		return 0, 0, err
-	}
-	fmt.Println(cmd)
-	fmt.Println(string(b))
-
-	return parseAccountQueryResponse(b, sdkVersion)
+	return 0, 0, fmt.Errorf("unknown account type: %s", acctType)
 }
 
 // Get account balance for a particular denom
 func getAccountBalance(address string, denom string, chain Chain) (math.Int, error) {
-
-	// [binary] query account balances <account> --output json
-	cmdArgs := []string{"query", "bank", "balances",
-		"--output", "json",
-		"--node", chain.Node,
-		address,
-	}
-
-	execCmd := exec.Command(chain.Binary, cmdArgs...)
-	fmt.Println(execCmd)
-	unsignedBytes, err := execCmd.CombinedOutput()
+	ab, err := newChainClient(chain).Balances(address)
 	if err != nil {
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println("call failed")
-		fmt.Println("-----------------------------------------------------------------")
-		fmt.Println(execCmd)
-		fmt.Println(string(unsignedBytes))
 		return math.ZeroInt(), err
 	}
-
-	var ab AccountBalance
-	err = json.Unmarshal(unsignedBytes, &ab)
-	if err != nil {
-		return math.ZeroInt(), fmt.Errorf("cannot parse balance")
-	}
 	for _, balance := range ab.Balances {
 		if strings.ToLower(balance.Denom) == strings.ToLower(denom) {
 			amount, ok := math.NewIntFromString(balance.Amount)
@@ -1381,29 +1343,37 @@ func getGas(config *Config) int64 {
 	}
 }
 
-func getFeesParameter(cmd *cobra.Command) (sdk.DecCoin, error) {
+// getFeesParameter returns the fees from the '--fees' flag. Without it, the error suggests
+// the gas limit multiplied by the gas price of the chain in the config
+func getFeesParameter(cmd *cobra.Command, conf *Config, chain Chain) (sdk.DecCoin, error) {
 	if cmd.Flags().Changed("fees") {
 		decCoin, err := sdk.ParseDecCoin(flagFees)
 		if err != nil {
 			return sdk.DecCoin{}, fmt.Errorf("error parsing the '--fees' parameter, please specify the amount and denom, e.g. 100uatom")
 		}
 		return decCoin, nil
-	} else {
-		return sdk.DecCoin{}, fmt.Errorf("please specify the '--fees' parameter")
 	}
+	if gasPrice, err := sdk.ParseDecCoin(chain.GasPrice); err == nil && chain.GasPrice != "" {
+		gas := getGas(conf)
+		amount := gasPrice.Amount.MulInt64(gas).Ceil()
+		return sdk.DecCoin{}, fmt.Errorf("please specify the '--fees' parameter, e.g. %s for %d gas at the gasprice %s of chain %s",
+			sdk.NewDecCoinFromDec(gasPrice.Denom, amount), gas, chain.GasPrice, chain.Name)
+	}
+	return sdk.DecCoin{}, fmt.Errorf("please specify the '--fees' parameter")
 }
 
-func listFilesInPath(svc *s3.S3, conf *Config, txDir string) ([]string, error) {
-	// list all items in bucket
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(conf.AWS.Bucket)})
+// list the names of the files in txDir
+func listFilesInPath(sess *session.Session, conf *Config, txDir string) ([]string, error) {
+	// list all items in txDir
+	objects, err := awsListObjects(sess, conf.AWS, strings.TrimSuffix(txDir, "/")+"/")
 	if err != nil {
 		return nil, err
 	}
 
 	fileNames := []string{}
-	for _, item := range resp.Contents {
+	for _, item := range objects {
 		itemKey := *item.Key
-		if strings.HasPrefix(itemKey, txDir) && !strings.HasSuffix(itemKey, "/") {
+		if !strings.HasSuffix(itemKey, "/") {
 			base := filepath.Base(itemKey)
 
 			// sanity check
@@ -1417,12 +1387,12 @@ func listFilesInPath(svc *s3.S3, conf *Config, txDir string) ([]string, error) {
 	return fileNames, nil
 }
 
+// move all files in txDir to the archive, so we can still see what was there
 func deleteAllFilesInPath(txDir string, conf *Config) error {
 
 	sess := awsSession(conf.AWS)
-	svc := s3.New(sess)
 
-	fileNames, err := listFilesInPath(svc, conf, txDir)
+	fileNames, err := listFilesInPath(sess, conf, txDir)
 	if err != nil {
 		return err
 	}
@@ -1432,35 +1402,18 @@ func deleteAllFilesInPath(txDir string, conf *Config) error {
 		fmt.Printf("no existing files in %s, nothing will be deleted\n", txDir)
 		return nil
 	} else {
-		fmt.Printf("found %d files in %s, deleting files...\n", len(fileNames), txDir)
+		fmt.Printf("found %d files in %s, moving files to the archive...\n", len(fileNames), txDir)
 	}
 
-	sep := "---------------------------------------------------------------------"
-	fmt.Println(sep)
-
-	// cleanup txDir in the bucket by deleting everything
-	for _, f := range fileNames {
-		awsString := aws.String(filepath.Join(txDir, f))
-		_, err = svc.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    awsString,
-		})
-		if err != nil {
-			return err
-		} else {
-			fmt.Printf("%v will be deleted...\n", *awsString)
-		}
-
-		err = svc.WaitUntilObjectNotExists(&s3.HeadObjectInput{
-			Bucket: aws.String(conf.AWS.Bucket),
-			Key:    awsString,
-		})
-		if err != nil {
-			return err
-		} else {
-			fmt.Printf("deleted %v !\n", *awsString)
-			fmt.Println(sep)
-		}
+	chainName, keyName, txIndex, err := parseTxDir(txDir)
+	if err != nil {
+		return err
 	}
-	return nil
+	record := ArchiveRecord{
+		Chain:  chainName,
+		Key:    keyName,
+		Index:  txIndex,
+		Action: "delete",
+	}
+	return archiveTx(sess, conf, txDir, fileNames, record)
 }
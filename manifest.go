@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// default file holding the private key used to sign manifests
+var defaultMemberKeyFile = ".multisig/member.key"
+
+// A member of the team, whose manifests we trust
+type Member struct {
+	Name   string `toml:"name"`   // same as the member's user in their config
+	PubKey string `toml:"pubkey"` // base64 ed25519 public key, see `multisig members keygen`
+}
+
+// Manifest of a pushed tx, signed by the member who pushed it, so signers and
+// broadcasters can check unsigned.json and signdata.json weren't swapped since.
+// It's bound to the chain/key queue of the tx so it can't be copied to another one.
+// The index isn't checked, as reindexing moves txs within their queue
+type Manifest struct {
+	TxDir          string `json:"txdir"` // chain/key/index the tx was pushed to
+	UnsignedSHA256 string `json:"unsigned_sha256"`
+	SignDataSHA256 string `json:"signdata_sha256"`
+	Signer         string `json:"signer"`
+	Timestamp      string `json:"timestamp"`
+	Signature      string `json:"signature,omitempty"`
+}
+
+// the bytes signed by the pusher: the manifest without its signature
+func (m Manifest) signBytes() ([]byte, error) {
+	m.Signature = ""
+	return json.Marshal(m)
+}
+
+func (c *Config) GetMember(name string) (Member, bool) {
+	for _, member := range c.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return Member{}, false
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func memberKeyFile(conf *Config) (string, error) {
+	if conf.MemberKey != "" {
+		return expandHome(conf.MemberKey)
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, defaultMemberKeyFile), nil
+}
+
+func loadMemberKey(conf *Config) (ed25519.PrivateKey, error) {
+	filename, err := memberKeyFile(conf)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid member key in %s", filename)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// write a manifest signed with our member key next to a pushed tx.
+// Manifests are only used if the config has members
+func writeManifest(sess *session.Session, conf *Config, txDir string, unsignedBytes, signDataBytes []byte) error {
+	if len(conf.Members) == 0 {
+		return nil
+	}
+
+	key, err := loadMemberKey(conf)
+	if err != nil {
+		return fmt.Errorf("cannot load your member key to sign the manifest (see `multisig members keygen`): %s", err)
+	}
+
+	manifest := Manifest{
+		TxDir:          txDir,
+		UnsignedSHA256: sha256Hex(unsignedBytes),
+		SignDataSHA256: sha256Hex(signDataBytes),
+		Signer:         conf.User,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+	signBytes, err := manifest.signBytes()
+	if err != nil {
+		return err
+	}
+	manifest.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signBytes))
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return awsUpload(sess, conf.AWS, txDir, manifestJSON, manifestBytes)
+}
+
+// check the manifest of a tx was signed by a known member and matches the unsigned tx and sign data.
+// Manifests are only checked if the config has members, a tx with a manifest can't be used without them
+func verifyManifest(sess *session.Session, conf *Config, txDir string, unsignedBytes, signDataBytes []byte) error {
+	manifestBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, manifestJSON)
+	if len(conf.Members) == 0 {
+		if isNoSuchKey(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot check whether %s has a manifest, refusing to continue: %s", txDir, err)
+		}
+		return fmt.Errorf("%s has a manifest but your config has no [[members]] to check it with, refusing to continue", txDir)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot fetch the manifest of %s, refusing to continue: %s", txDir, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return fmt.Errorf("cannot parse the manifest of %s: %s", txDir, err)
+	}
+
+	member, found := conf.GetMember(manifest.Signer)
+	if !found {
+		return fmt.Errorf("the manifest of %s was signed by %s, who is not a known member", txDir, manifest.Signer)
+	}
+	pubKey, err := base64.StdEncoding.DecodeString(member.PubKey)
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid pubkey for member %s in the config", member.Name)
+	}
+	signature, err := base64.StdEncoding.DecodeString(manifest.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature in the manifest of %s", txDir)
+	}
+	signBytes, err := manifest.signBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), signBytes, signature) {
+		return fmt.Errorf("the manifest of %s was not signed by %s, refusing to continue", txDir, manifest.Signer)
+	}
+
+	if filepath.Dir(manifest.TxDir) != filepath.Dir(txDir) {
+		return fmt.Errorf("the manifest in %s was made for %s, refusing to continue", txDir, manifest.TxDir)
+	}
+	if manifest.UnsignedSHA256 != sha256Hex(unsignedBytes) {
+		return fmt.Errorf("%s in %s does not match its manifest, it was changed after being pushed by %s", unsignedJSON, txDir, manifest.Signer)
+	}
+	if manifest.SignDataSHA256 != sha256Hex(signDataBytes) {
+		return fmt.Errorf("%s in %s does not match its manifest, it was changed after being pushed by %s", signDataJSON, txDir, manifest.Signer)
+	}
+
+	fmt.Printf("manifest of %s signed by %s on %s\n", txDir, manifest.Signer, manifest.Timestamp)
+	return nil
+}
+
+// generate a new member key and print its public key, to be added to the members of the team
+func cmdMembersKeygen(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	filename, err := memberKeyFile(conf)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filename); err == nil && !flagForce {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote your member key to %s\n", filename)
+	fmt.Println("add yourself to the members of everyone's local config:")
+	fmt.Println()
+	fmt.Println("[[members]]")
+	fmt.Printf("name = %q\n", conf.User)
+	fmt.Printf("pubkey = %q\n", base64.StdEncoding.EncodeToString(pub))
+	return nil
+}
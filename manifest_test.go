@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	alice := newTestMember(t, "alice")
+	alice.AWS = bucket
+	mallory := newTestMember(t, "mallory")
+	mallory.AWS = bucket
+
+	txDir := "cosmoshub/validator/0"
+	unsigned, signData := []byte(`{"body":{"messages":[]}}`), []byte(`{"account":1,"sequence":2,"chain_id":"cosmoshub-4"}`)
+	if err := writeManifest(sess, alice, txDir, unsigned, signData); err != nil {
+		t.Fatal(err)
+	}
+	manifest := objects[txDir+"/manifest.json"]
+
+	// mallory signs a manifest for other files with their own key but in alice's name
+	forged := func() []byte {
+		if err := writeManifest(sess, mallory, "cosmoshub/validator/9", []byte("other"), signData); err != nil {
+			t.Fatal(err)
+		}
+		var m Manifest
+		if err := json.Unmarshal(objects["cosmoshub/validator/9/manifest.json"], &m); err != nil {
+			t.Fatal(err)
+		}
+		m.Signer = "alice"
+		b, _ := json.Marshal(m)
+		return b
+	}()
+
+	cases := []struct {
+		name     string
+		conf     *Config
+		manifest []byte
+		unsigned []byte
+		signData []byte
+		wantErr  string
+	}{
+		{name: "valid", conf: alice, manifest: manifest, unsigned: unsigned, signData: signData},
+		{name: "changed unsigned tx", conf: alice, manifest: manifest, unsigned: []byte(`{}`), signData: signData, wantErr: "unsigned.json in cosmoshub/validator/0 does not match"},
+		{name: "changed sign data", conf: alice, manifest: manifest, unsigned: unsigned, signData: []byte(`{}`), wantErr: "signdata.json in cosmoshub/validator/0 does not match"},
+		{name: "missing manifest", conf: alice, unsigned: unsigned, signData: signData, wantErr: "cannot fetch the manifest"},
+		{name: "unknown signer", conf: &Config{AWS: bucket, Members: mallory.Members}, manifest: manifest, unsigned: unsigned, signData: signData, wantErr: "not a known member"},
+		{name: "forged signature", conf: alice, manifest: forged, unsigned: []byte("other"), signData: signData, wantErr: "was not signed by alice"},
+		{name: "no members and no manifest", conf: &Config{AWS: bucket}, unsigned: unsigned, signData: signData},
+		{name: "no members but a manifest", conf: &Config{AWS: bucket}, manifest: manifest, unsigned: unsigned, signData: signData, wantErr: "no [[members]] to check it with"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.manifest != nil {
+				objects[txDir+"/manifest.json"] = tc.manifest
+			} else {
+				delete(objects, txDir+"/manifest.json")
+			}
+			err := verifyManifest(sess, tc.conf, txDir, tc.unsigned, tc.signData)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/spf13/cobra"
+)
+
+// the public keys members sign txs with are registered here, outside of the chain/key directories
+var membersDir = "members"
+
+// MemberRecord lists the public keys a member signs txs with, see `multisig members register`
+type MemberRecord struct {
+	Name       string   `json:"name"`
+	PubKeys    []string `json:"pubkeys"` // base64 secp256k1 public keys
+	Registered string   `json:"registered"`
+	Signature  string   `json:"signature,omitempty"`
+}
+
+// the bytes signed with the member key: the record without its signature
+func (r MemberRecord) signBytes() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+func (r MemberRecord) hasPubKey(pubKey string) bool {
+	return contains(r.PubKeys, pubKey)
+}
+
+// fetch the record of a member from the registry, or nil if they didn't register.
+// Once the config has members, the record must be signed by that member. Without
+// members it can't be checked and is returned as is
+func fetchMemberRecord(sess *session.Session, conf *Config, name string) (*MemberRecord, error) {
+	b, err := awsDownloadBytes(sess, conf.AWS, membersDir, name+".json")
+	if isNoSuchKey(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot fetch the registered pubkeys of %s: %s", name, err)
+	}
+
+	var record MemberRecord
+	if err := json.Unmarshal(b, &record); err != nil {
+		return nil, fmt.Errorf("cannot parse the registered pubkeys of %s: %s", name, err)
+	}
+	if record.Name != name {
+		return nil, fmt.Errorf("the registered pubkeys of %s are for %s", name, record.Name)
+	}
+	if len(conf.Members) == 0 {
+		return &record, nil
+	}
+
+	member, found := conf.GetMember(name)
+	if !found {
+		return nil, fmt.Errorf("%s registered pubkeys but is not a known member", name)
+	}
+	pubKey, err := base64.StdEncoding.DecodeString(member.PubKey)
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid pubkey for member %s in the config", member.Name)
+	}
+	signature, err := base64.StdEncoding.DecodeString(record.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature in the registered pubkeys of %s", name)
+	}
+	signBytes, err := record.signBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), signBytes, signature) {
+		return nil, fmt.Errorf("the registered pubkeys of %s were not signed by %s, refusing to use them", name, name)
+	}
+	return &record, nil
+}
+
+// check each <user>.json signature was made with a key registered by that user.
+// Once the config has members, signatures of users who didn't register or whose record
+// can't be verified are refused, unless the config allows unregistered signers.
+// Without members, they are only warned about
+func checkSignatureAuthors(sess *session.Session, conf *Config, txDir string, sigFileNames []string, sigBytes map[string][]byte) error {
+	enforce := len(conf.Members) > 0 && !conf.AllowUnregistered
+	if len(conf.Members) == 0 && len(sigFileNames) > 0 {
+		fmt.Println("WARNING: no [[members]] in your config, cannot check the registered pubkeys of the signers were published by them")
+	}
+	for _, f := range sigFileNames {
+		name := strings.TrimSuffix(f, ".json")
+		pubKey, err := parsePubKey(sigBytes[f])
+		if err != nil {
+			return fmt.Errorf("cannot read the public key of %s in %s: %s", f, txDir, err)
+		}
+		record, err := fetchMemberRecord(sess, conf, name)
+		if err != nil {
+			if enforce {
+				return err
+			}
+			fmt.Printf("WARNING: %s, cannot check %s\n", err, f)
+			continue
+		}
+		if record == nil {
+			if enforce {
+				return fmt.Errorf("%s has not registered their pubkeys (see `multisig members register`), refusing %s in %s", name, f, txDir)
+			}
+			fmt.Printf("WARNING: %s has not registered their pubkeys (see `multisig members register`), cannot check %s\n", name, f)
+			continue
+		}
+		if !record.hasPubKey(pubKey) {
+			return fmt.Errorf("%s in %s was signed with %s, which is not registered by %s, refusing to continue", f, txDir, pubKey, name)
+		}
+		fmt.Printf("%s signed by %s\n", f, name)
+	}
+	return nil
+}
+
+// resolve the members of a new multisig to public keys: base64 public keys are kept
+// as is, anything else is the name of a member with a single registered pubkey
+func resolveMemberPubKeys(sess *session.Session, conf *Config, members []string) ([]string, error) {
+	pubKeys := []string{}
+	for _, m := range members {
+		m = strings.TrimSpace(m)
+		if b, err := base64.StdEncoding.DecodeString(m); err == nil && len(b) == secp256k1.PubKeySize {
+			pubKeys = append(pubKeys, m)
+			continue
+		}
+		record, err := fetchMemberRecord(sess, conf, m)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, fmt.Errorf("%s is neither a public key nor a registered member", m)
+		}
+		if len(record.PubKeys) != 1 {
+			return nil, fmt.Errorf("%s registered %d pubkeys, pass the one to use instead of their name: %s", m, len(record.PubKeys), strings.Join(record.PubKeys, ", "))
+		}
+		if len(conf.Members) == 0 {
+			fmt.Printf("WARNING: no [[members]] in your config, cannot check the registered pubkeys of %s were published by them\n", m)
+		}
+		fmt.Printf("using the registered pubkey of %s: %s\n", m, record.PubKeys[0])
+		pubKeys = append(pubKeys, record.PubKeys[0])
+	}
+	return pubKeys, nil
+}
+
+// read the public key of a local signer from the keystore of a chain's binary
+func readSignerPubKey(conf *Config, chain Chain, signer Signer) (string, error) {
+	home, backend, err := signerKeystore(conf, chain, signer)
+	if err != nil {
+		return "", err
+	}
+
+	cmdArgs := []string{"keys", "show", signer.Name, "--output", "json"}
+	if backend != "" {
+		cmdArgs = append(cmdArgs, "--keyring-backend", backend)
+	}
+	if home != "" {
+		cmdArgs = append(cmdArgs, "--home", home)
+	}
+	cmd := exec.Command(chain.Binary, cmdArgs...)
+	b, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s from the %s keystore: %s", signer.Name, chain.Binary, err)
+	}
+	return parsePubKey(b)
+}
+
+// the public keys of our local signers across the chains and keys of the config
+func localSignerPubKeys(conf *Config) ([]string, error) {
+	pubKeys := []string{}
+	seen := map[string]bool{}
+	for _, chain := range conf.Chains {
+		keys := append([]Key{{}}, conf.Keys...)
+		for _, key := range keys {
+			signer, err := localSigner(conf, chain, key)
+			if err != nil {
+				continue
+			}
+			id := strings.Join([]string{chain.Binary, signer.Name, signer.Home, signer.KeyringBackend}, "|")
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			pubKey, err := readSignerPubKey(conf, chain, signer)
+			if err != nil {
+				fmt.Printf("WARNING: skipping %s: %s\n", chain.Name, err)
+				continue
+			}
+			if !contains(pubKeys, pubKey) {
+				fmt.Printf("found %s in the %s keystore: %s\n", signer.Name, chain.Binary, pubKey)
+				pubKeys = append(pubKeys, pubKey)
+			}
+		}
+	}
+	if len(pubKeys) == 0 {
+		return nil, fmt.Errorf("no local signing keys found, use --pubkey or configure the [[signers]] or the from of the chains")
+	}
+	return pubKeys, nil
+}
+
+// publish the public keys we sign txs with to members/<user>.json
+func cmdMembersRegister(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	var pubKeys []string
+	if len(flagPubKeys) > 0 {
+		for _, k := range flagPubKeys {
+			k = strings.TrimSpace(k)
+			if b, err := base64.StdEncoding.DecodeString(k); err != nil || len(b) != secp256k1.PubKeySize {
+				return fmt.Errorf("%s is not a base64 secp256k1 public key", k)
+			}
+			pubKeys = append(pubKeys, k)
+		}
+	} else {
+		pubKeys, err = localSignerPubKeys(conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := MemberRecord{
+		Name:       conf.User,
+		PubKeys:    pubKeys,
+		Registered: time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(conf.Members) > 0 {
+		key, err := loadMemberKey(conf)
+		if err != nil {
+			return fmt.Errorf("cannot load your member key to sign your pubkeys (see `multisig members keygen`): %s", err)
+		}
+		signBytes, err := record.signBytes()
+		if err != nil {
+			return err
+		}
+		record.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signBytes))
+	}
+
+	recordBytes, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := awsUpload(sess, conf.AWS, membersDir, conf.User+".json", recordBytes); err != nil {
+		return err
+	}
+	fmt.Printf("registered %d pubkeys for %s in %s\n", len(pubKeys), conf.User, filepath.Join(membersDir, conf.User+".json"))
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// a <user>.json signature made with pubKey
+func testSignature(pubKey string) []byte {
+	return []byte(fmt.Sprintf(`{"signatures":[{"public_key":{"@type":"%s","key":"%s"},"data":{},"sequence":"1"}]}`, secp256k1PubKeyType, pubKey))
+}
+
+func TestCheckSignatureAuthors(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	alice := newTestMember(t, "alice")
+	alice.AWS = bucket
+	bob := newTestMember(t, "bob")
+	alice.Members = append(alice.Members, bob.Members...)
+
+	aliceKey := base64.StdEncoding.EncodeToString(append([]byte{2}, make([]byte, 32)...))
+	otherKey := base64.StdEncoding.EncodeToString(append([]byte{3}, make([]byte, 32)...))
+
+	// alice registers their key, signed with their member key
+	record := MemberRecord{Name: "alice", PubKeys: []string{aliceKey}, Registered: "2024-01-01T00:00:00Z"}
+	key, err := loadMemberKey(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signBytes, err := record.signBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signBytes))
+	b, _ := json.Marshal(record)
+	objects["members/alice.json"] = b
+
+	// a record for bob that bob didn't sign
+	b, _ = json.Marshal(MemberRecord{Name: "bob", PubKeys: []string{otherKey}})
+	objects["members/bob.json"] = b
+
+	noMembers := &Config{AWS: bucket}
+	allowing := *alice
+	allowing.AllowUnregistered = true
+
+	cases := []struct {
+		name    string
+		conf    *Config
+		sigs    map[string][]byte
+		wantErr string
+	}{
+		{name: "registered key", conf: alice, sigs: map[string][]byte{"alice.json": testSignature(aliceKey)}},
+		{name: "unregistered key", conf: alice, sigs: map[string][]byte{"alice.json": testSignature(otherKey)}, wantErr: "which is not registered by alice"},
+		{name: "unregistered signer", conf: alice, sigs: map[string][]byte{"carol.json": testSignature(otherKey)}, wantErr: "carol has not registered"},
+		{name: "unsigned record", conf: alice, sigs: map[string][]byte{"bob.json": testSignature(otherKey)}, wantErr: "were not signed by bob"},
+		{name: "unregistered signer allowed", conf: &allowing, sigs: map[string][]byte{"carol.json": testSignature(otherKey), "bob.json": testSignature(otherKey)}},
+		{name: "no members, unregistered signer", conf: noMembers, sigs: map[string][]byte{"carol.json": testSignature(otherKey)}},
+		{name: "no members, unsigned record", conf: noMembers, sigs: map[string][]byte{"bob.json": testSignature(otherKey)}},
+		{name: "no members, unregistered key", conf: noMembers, sigs: map[string][]byte{"alice.json": testSignature(otherKey)}, wantErr: "which is not registered by alice"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			names := []string{}
+			for name := range tc.sigs {
+				names = append(names, name)
+			}
+			err := checkSignatureAuthors(sess, tc.conf, "cosmoshub/validator/0", names, tc.sigs)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
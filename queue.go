@@ -0,0 +1,569 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// a pending tx in the queue of a chain/key pair
+type queuedTx struct {
+	Index    int
+	Files    []string
+	Modified time.Time // when unsigned.json was last written
+}
+
+// sequence of a pending tx compared with the on-chain sequence of the key
+type sequenceStatus struct {
+	Index    int
+	Sequence int // sequence in the sign data
+	Expected int // on-chain sequence + position among the ordered txs of the queue
+	Stale    bool
+
+	Unordered bool
+	Expired   bool // the timeout of an unordered tx has passed
+}
+
+// describe what is wrong with the sequence of a pending tx, if anything
+func (s sequenceStatus) conflict() string {
+	if s.Unordered {
+		if s.Expired {
+			return "is unordered and timed out, it can no longer be broadcast"
+		}
+		return ""
+	}
+	if s.Stale {
+		return fmt.Sprintf("has sequence %d which was already used on-chain, it can no longer be broadcast", s.Sequence)
+	} else if s.Sequence != s.Expected {
+		return fmt.Sprintf("has sequence %d, expected %d", s.Sequence, s.Expected)
+	}
+	return ""
+}
+
+// list the pending txs of a chain/key pair, by index
+func listQueue(sess *session.Session, conf *Config, chainName, keyName string) ([]queuedTx, error) {
+	keyDir := filepath.Join(chainName, keyName) + "/"
+	objects, err := awsListObjects(sess, conf.AWS, keyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := map[int]*queuedTx{}
+	for _, item := range objects {
+		// <chain>/<key>/<index>/<file>
+		spl := strings.Split(strings.TrimPrefix(*item.Key, keyDir), "/")
+		if len(spl) != 2 || spl[1] == "" {
+			continue
+		}
+		index, err := strconv.Atoi(spl[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read number after %s in path %s", keyDir, *item.Key)
+		}
+		tx, found := txs[index]
+		if !found {
+			tx = &queuedTx{Index: index}
+			txs[index] = tx
+		}
+		tx.Files = append(tx.Files, spl[1])
+		if spl[1] == unsignedJSON && item.LastModified != nil {
+			tx.Modified = *item.LastModified
+		}
+	}
+
+	queue := []queuedTx{}
+	for _, tx := range txs {
+		queue = append(queue, *tx)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].Index < queue[j].Index
+	})
+	return queue, nil
+}
+
+// renumber the pending txs of a chain/key pair to 0, 1, 2, ... keeping their order,
+// so the next tx to broadcast is always index 0 and `tx push -x` derives the right sequence.
+// Files are moved as they are, manifests included, as they aren't bound to the index.
+// The caller must hold the lock of the queue.
+//
+// A move copies unsigned.json last and deletes it first, so if it's interrupted the tx is
+// complete in at least one of the two directories, and the other only has copies without an
+// unsigned.json, which are removed when reindexing again. If it's interrupted between the two,
+// the tx is left in both directories and the copy with the higher index must be deleted
+func reindexQueue(sess *session.Session, conf *Config, chainName, keyName string) error {
+	queue, err := listQueue(sess, conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+	queue, err = removeIncompleteTxs(sess, conf, chainName, keyName, queue)
+	if err != nil {
+		return err
+	}
+
+	moved := 0
+	for i, tx := range queue {
+		if tx.Index == i {
+			continue
+		}
+		// indices below tx.Index have already been compacted, so i is free
+		srcDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index))
+		dstDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", i))
+		files := unsignedLast(tx.Files)
+		for _, f := range files {
+			if err := awsCopy(sess, conf.AWS, filepath.Join(srcDir, f), filepath.Join(dstDir, f)); err != nil {
+				return fmt.Errorf("cannot move %s to %s: %s", srcDir, dstDir, err)
+			}
+		}
+		for j := len(files) - 1; j >= 0; j-- {
+			if err := awsDelete(sess, conf.AWS, filepath.Join(srcDir, files[j])); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("moved %s to %s\n", srcDir, dstDir)
+		moved++
+	}
+
+	if moved == 0 {
+		fmt.Printf("%s/%s is already indexed from 0, nothing to do\n", chainName, keyName)
+	}
+	return nil
+}
+
+// the files of a tx with unsigned.json last, which marks a copied tx as complete
+func unsignedLast(files []string) []string {
+	sorted := []string{}
+	for _, f := range files {
+		if f != unsignedJSON {
+			sorted = append(sorted, f)
+		}
+	}
+	if len(sorted) < len(files) {
+		sorted = append(sorted, unsignedJSON)
+	}
+	return sorted
+}
+
+// remove the directories without an unsigned.json left by an interrupted reindex, returning the
+// complete txs. Their files must all be in another tx of the queue, or they're left for a human to look at
+func removeIncompleteTxs(sess *session.Session, conf *Config, chainName, keyName string, queue []queuedTx) ([]queuedTx, error) {
+	complete := []queuedTx{}
+	incomplete := []queuedTx{}
+	for _, tx := range queue {
+		if contains(tx.Files, unsignedJSON) {
+			complete = append(complete, tx)
+		} else {
+			incomplete = append(incomplete, tx)
+		}
+	}
+
+	for _, tx := range incomplete {
+		txDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index))
+		for _, f := range tx.Files {
+			found := false
+			for _, other := range complete {
+				found = found || contains(other.Files, f)
+			}
+			if !found {
+				return nil, fmt.Errorf("%s has no %s but has %s, which isn't in any other tx of the queue, please check it with `multisig raw cat`", txDir, unsignedJSON, f)
+			}
+		}
+		for _, f := range tx.Files {
+			if err := awsDelete(sess, conf.AWS, filepath.Join(txDir, f)); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Printf("removed the leftovers of an interrupted move in %s\n", txDir)
+	}
+	return complete, nil
+}
+
+// query the account and sequence numbers of a key on a chain
+func queryAccSeq(chain Chain, key Key) (int, int, error) {
+	address, err := bech32ify(key.Address, chain.Prefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	return newChainClient(chain).AccSeq(address)
+}
+
+// fetch the sign data of a pending tx
+func fetchSignData(sess *session.Session, conf *Config, txDir string) (SignData, error) {
+	var signData SignData
+	signDataBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, signDataJSON)
+	if err != nil {
+		return signData, fmt.Errorf("cannot fetch the sign data of %s: %s", txDir, err)
+	}
+	if err := json.Unmarshal(signDataBytes, &signData); err != nil {
+		return signData, fmt.Errorf("cannot parse the sign data of %s: %s", txDir, err)
+	}
+	return signData, nil
+}
+
+// compare the sequence of each pending tx with the on-chain sequence. The i-th ordered
+// tx in the queue is expected to use the on-chain sequence + i, unordered txs don't use one
+func checkQueueSequences(sess *session.Session, conf *Config, chainName, keyName string, queue []queuedTx, onChainSeq int) ([]sequenceStatus, error) {
+	statuses := []sequenceStatus{}
+	ordered := 0
+	for _, tx := range queue {
+		signData, err := fetchSignData(sess, conf, filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index)))
+		if err != nil {
+			return nil, err
+		}
+		if signData.Unordered {
+			statuses = append(statuses, sequenceStatus{
+				Index:     tx.Index,
+				Unordered: true,
+				Expired:   checkTimeout(signData) != nil,
+			})
+			continue
+		}
+		statuses = append(statuses, sequenceStatus{
+			Index:    tx.Index,
+			Sequence: signData.Sequence,
+			Expected: onChainSeq + ordered,
+			Stale:    signData.Sequence < onChainSeq,
+		})
+		ordered++
+	}
+	return statuses, nil
+}
+
+// list the pending txs of a chain/key pair and compare their sequences with the on-chain one.
+// Returns the queue, the status of each tx, and the on-chain sequence
+func queueSequences(sess *session.Session, conf *Config, chain Chain, key Key) ([]queuedTx, []sequenceStatus, int, error) {
+	queue, err := listQueue(sess, conf, chain.Name, key.Name)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(queue) == 0 {
+		return queue, nil, 0, nil
+	}
+	_, onChainSeq, err := queryAccSeq(chain, key)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	statuses, err := checkQueueSequences(sess, conf, chain.Name, key.Name, queue, onChainSeq)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return queue, statuses, onChainSeq, nil
+}
+
+// the names of the signatures of a tx, ie. everything except unsigned.json, signdata.json and manifest.json
+func signatureFiles(fileNames []string) []string {
+	sigFileNames := []string{}
+	for _, f := range fileNames {
+		if f == unsignedJSON || f == signDataJSON || f == manifestJSON {
+			continue
+		}
+		sigFileNames = append(sigFileNames, f)
+	}
+	return sigFileNames
+}
+
+// renumber the pending txs of a chain/key pair, and report those whose sequence is stale
+func cmdReindex(cmd *cobra.Command, args []string) error {
+	chainName := args[0]
+	keyName := args[1]
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	chain, found := conf.GetChain(chainName)
+	if !found {
+		return fmt.Errorf("chain %s not found in config", chainName)
+	}
+	key, found := conf.GetKey(keyName)
+	if !found {
+		return fmt.Errorf("key %s not found in config", keyName)
+	}
+
+	sess := awsSession(conf.AWS)
+
+	release, err := acquireLock(sess, conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := reindexQueue(sess, conf, chainName, keyName); err != nil {
+		return err
+	}
+
+	if flagREST != "" {
+		chain.REST = flagREST
+	}
+
+	_, statuses, _, err := queueSequences(sess, conf, chain, key)
+	if err != nil {
+		fmt.Printf("WARNING: cannot compare the pending txs with the on-chain sequence: %s\n", err)
+		return nil
+	}
+	for _, status := range statuses {
+		if msg := status.conflict(); msg != "" {
+			fmt.Printf("WARNING: tx %d %s\n", status.Index, msg)
+		}
+	}
+	return nil
+}
+
+// the chain/key pairs with pending txs in the bucket, which are in the config
+func pendingPairs(sess *session.Session, conf *Config) ([][2]string, error) {
+	objects, err := awsListObjects(sess, conf.AWS, "")
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := [][2]string{}
+	seen := map[string]bool{}
+	for _, item := range objects {
+		// <chain>/<key>/<index>/<file>
+		spl := strings.Split(*item.Key, "/")
+		if len(spl) != 4 || spl[3] == "" || seen[spl[0]+"/"+spl[1]] {
+			continue
+		}
+		if _, err := strconv.Atoi(spl[2]); err != nil {
+			continue
+		}
+		_, chainFound := conf.GetChain(spl[0])
+		_, keyFound := conf.GetKey(spl[1])
+		if !chainFound || !keyFound {
+			continue
+		}
+		seen[spl[0]+"/"+spl[1]] = true
+		pairs = append(pairs, [2]string{spl[0], spl[1]})
+	}
+	return pairs, nil
+}
+
+// compare the sequence of every pending tx with the on-chain sequence of its key,
+// for a chain/key pair or every pair with pending txs
+func cmdCheck(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		return fmt.Errorf("must specify both a chain name and a key name, or neither")
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	pairs := [][2]string{}
+	if len(args) == 2 {
+		pairs = append(pairs, [2]string{args[0], args[1]})
+	} else {
+		pairs, err = pendingPairs(sess, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	conflicts := 0
+	for _, pair := range pairs {
+		chain, found := conf.GetChain(pair[0])
+		if !found {
+			return fmt.Errorf("chain %s not found in config", pair[0])
+		}
+		key, found := conf.GetKey(pair[1])
+		if !found {
+			return fmt.Errorf("key %s not found in config", pair[1])
+		}
+
+		if flagREST != "" {
+			chain.REST = flagREST
+		}
+
+		queue, statuses, onChainSeq, err := queueSequences(sess, conf, chain, key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s/%s: on-chain sequence %d, %d pending txs\n", chain.Name, key.Name, onChainSeq, len(queue))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INDEX\tSEQUENCE\tEXPECTED\tSIGNATURES\tSTATUS")
+		for i, status := range statuses {
+			msg := status.conflict()
+			if msg != "" {
+				conflicts++
+			} else if status.Unordered {
+				msg = "ok (unordered)"
+			} else {
+				msg = "ok"
+			}
+			sequence, expected := fmt.Sprint(status.Sequence), fmt.Sprint(status.Expected)
+			if status.Unordered {
+				sequence, expected = "-", "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", status.Index, sequence, expected, len(signatureFiles(queue[i].Files)), msg)
+		}
+		w.Flush()
+		if len(queue) > 0 && queue[0].Index != 0 {
+			fmt.Printf("the queue doesn't start at index 0, run `multisig reindex %s %s`\n", chain.Name, key.Name)
+		}
+	}
+
+	if conflicts > 0 {
+		return fmt.Errorf("found %d txs that can't be broadcast as is, run `multisig resequence <chain name> <key name>` to fix their sequence, or push timed out unordered txs again", conflicts)
+	}
+	return nil
+}
+
+// give every pending tx of a chain/key pair the sequence it will be broadcast with,
+// moving the signatures made for the wrong sequence aside to the archive
+func cmdResequence(cmd *cobra.Command, args []string) error {
+	chainName := args[0]
+	keyName := args[1]
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	chain, found := conf.GetChain(chainName)
+	if !found {
+		return fmt.Errorf("chain %s not found in config", chainName)
+	}
+	key, found := conf.GetKey(keyName)
+	if !found {
+		return fmt.Errorf("key %s not found in config", keyName)
+	}
+
+	if flagREST != "" {
+		chain.REST = flagREST
+	}
+
+	sess := awsSession(conf.AWS)
+
+	release, err := acquireLock(sess, conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return resequenceQueue(sess, conf, chain, key)
+}
+
+// rewrite the sign data of the pending txs of a chain/key pair with the wrong sequence.
+// The caller must hold the lock of the queue
+func resequenceQueue(sess *session.Session, conf *Config, chain Chain, key Key) error {
+	chainName, keyName := chain.Name, key.Name
+
+	// the manifests of the resequenced txs are signed again by us, so make sure we can before changing anything
+	if len(conf.Members) > 0 {
+		if _, err := loadMemberKey(conf); err != nil {
+			return fmt.Errorf("cannot load your member key to sign the manifests of the resequenced txs (see `multisig members keygen`): %s", err)
+		}
+	}
+
+	// the expected sequence depends on the position in the queue, so start from 0
+	if err := reindexQueue(sess, conf, chainName, keyName); err != nil {
+		return err
+	}
+
+	queue, statuses, _, err := queueSequences(sess, conf, chain, key)
+	if err != nil {
+		return err
+	}
+
+	resequenced := 0
+	for i, status := range statuses {
+		// unordered txs have no sequence to fix, an expired one must be pushed again
+		if status.Unordered || status.conflict() == "" {
+			continue
+		}
+		txDir := filepath.Join(chainName, keyName, fmt.Sprintf("%d", status.Index))
+
+		unsignedBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, unsignedJSON)
+		if err != nil {
+			return err
+		}
+		signDataBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, signDataJSON)
+		if err != nil {
+			return err
+		}
+		// don't re-sign the manifest of a tx that was tampered with
+		if err := verifyManifest(sess, conf, txDir, unsignedBytes, signDataBytes); err != nil {
+			return err
+		}
+
+		var signData SignData
+		if err := json.Unmarshal(signDataBytes, &signData); err != nil {
+			return err
+		}
+		signData.Sequence = status.Expected
+		signDataBytes, err = json.Marshal(signData)
+		if err != nil {
+			return err
+		}
+
+		// the signatures were made for the old sequence and can't be used anymore
+		sigFileNames := signatureFiles(queue[i].Files)
+		if len(sigFileNames) > 0 {
+			record := ArchiveRecord{
+				Chain:       chainName,
+				Key:         keyName,
+				Index:       status.Index,
+				Action:      "resequence",
+				Description: fmt.Sprintf("signatures for sequence %d, the tx now uses sequence %d", status.Sequence, status.Expected),
+			}
+			if err := archiveTx(sess, conf, txDir, sigFileNames, record); err != nil {
+				return err
+			}
+		}
+
+		if err := awsUpload(sess, conf.AWS, txDir, signDataJSON, signDataBytes); err != nil {
+			return err
+		}
+		if err := writeManifest(sess, conf, txDir, unsignedBytes, signDataBytes); err != nil {
+			return err
+		}
+
+		fmt.Printf("tx %d now uses sequence %d instead of %d, %d signatures must be made again\n", status.Index, status.Expected, status.Sequence, len(sigFileNames))
+		resequenced++
+	}
+
+	if resequenced == 0 {
+		fmt.Printf("all pending txs of %s/%s have the right sequence, nothing to do\n", chainName, keyName)
+	}
+	return nil
+}
+
+// check the fees of the txs pushed from a report can be computed on every chain they go to,
+// before pushing any of them
+func checkPushFees(cmd *cobra.Command, conf *Config, chainNames []string) error {
+	for _, chainName := range chainNames {
+		chain, found := conf.GetChain(chainName)
+		if !found {
+			return fmt.Errorf("chain %s not found in config", chainName)
+		}
+		if _, err := getFeesParameter(cmd, conf, chain); err != nil {
+			return fmt.Errorf("cannot push txs to %s: %s", chainName, err)
+		}
+	}
+	return nil
+}
+
+// push a tx with a tx command after the pending txs of a chain/key pair, if any.
+// The tx and progress are printed to stderr, so stdout only has the report
+func pushQueued(chainName, keyName, description string, push func(opts pushOptions) error) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	queue, err := listQueue(awsSession(conf.AWS), conf, chainName, keyName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "pushing a tx to %s/%s: %s\n", chainName, keyName, description)
+	return push(pushOptions{additional: len(queue) > 0, description: description, out: os.Stderr})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// a member whose key is written to a temporary file, as a config using it
+func newTestMember(t *testing.T, name string) *Config {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "member.key")
+	if err := os.WriteFile(filename, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &Config{
+		User:      name,
+		MemberKey: filename,
+		Members:   []Member{{Name: name, PubKey: base64.StdEncoding.EncodeToString(pub)}},
+	}
+}
+
+// the names of the objects in the bucket under prefix
+func objectNames(objects map[string][]byte, prefix string) []string {
+	names := []string{}
+	for name := range objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, strings.TrimPrefix(name, prefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReindexQueue(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	alice := newTestMember(t, "alice")
+	alice.AWS = bucket
+	sess := awsSession(bucket)
+
+	// alice pushes two txs with manifests, which end up at indices 2 and 5
+	for _, index := range []string{"2", "5"} {
+		txDir := "cosmoshub/validator/" + index
+		unsigned, signData := []byte(`{"tx":`+index+`}`), []byte(`{"sequence":`+index+`}`)
+		objects[txDir+"/unsigned.json"] = unsigned
+		objects[txDir+"/signdata.json"] = signData
+		if err := writeManifest(sess, alice, txDir, unsigned, signData); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objects["cosmoshub/validator/2/bob.json"] = []byte(`{"signature":"bob"}`)
+	manifest := objects["cosmoshub/validator/5/manifest.json"]
+
+	// bob reindexes without a member key of their own
+	bob := &Config{User: "bob", MemberKey: filepath.Join(t.TempDir(), "missing.key"), Members: alice.Members, AWS: bucket}
+	if err := reindexQueue(sess, bob, "cosmoshub", "validator"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0/bob.json", "0/manifest.json", "0/signdata.json", "0/unsigned.json", "1/manifest.json", "1/signdata.json", "1/unsigned.json"}
+	if got := objectNames(objects, "cosmoshub/validator/"); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// the manifests are moved as they are and still verify in the queue
+	if !bytes.Equal(objects["cosmoshub/validator/1/manifest.json"], manifest) {
+		t.Fatal("the manifest was changed by the move")
+	}
+	txDir := "cosmoshub/validator/1"
+	if err := verifyManifest(sess, bob, txDir, objects[txDir+"/unsigned.json"], objects[txDir+"/signdata.json"]); err != nil {
+		t.Fatal(err)
+	}
+	// but not in another queue
+	objects["osmosis/validator/0/manifest.json"] = manifest
+	if err := verifyManifest(sess, bob, "osmosis/validator/0", objects[txDir+"/unsigned.json"], objects[txDir+"/signdata.json"]); err == nil {
+		t.Fatal("expected a manifest copied to another queue to be refused")
+	}
+}
+
+func TestReindexQueueInterrupted(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	conf := &Config{User: "bob", AWS: bucket}
+	sess := awsSession(bucket)
+
+	// tx 3 was moved to 0 but only its unsigned.json was deleted, and tx 4 was being copied to 1
+	objects["cosmoshub/validator/0/unsigned.json"] = []byte("a")
+	objects["cosmoshub/validator/0/signdata.json"] = []byte("a")
+	objects["cosmoshub/validator/0/alice.json"] = []byte("a")
+	objects["cosmoshub/validator/3/signdata.json"] = []byte("a")
+	objects["cosmoshub/validator/3/alice.json"] = []byte("a")
+	objects["cosmoshub/validator/1/signdata.json"] = []byte("b")
+	objects["cosmoshub/validator/4/unsigned.json"] = []byte("b")
+	objects["cosmoshub/validator/4/signdata.json"] = []byte("b")
+
+	if err := reindexQueue(sess, conf, "cosmoshub", "validator"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0/alice.json", "0/signdata.json", "0/unsigned.json", "1/signdata.json", "1/unsigned.json"}
+	if got := objectNames(objects, "cosmoshub/validator/"); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if string(objects["cosmoshub/validator/1/unsigned.json"]) != "b" {
+		t.Fatal("tx 4 was not moved to 1")
+	}
+
+	// a directory without unsigned.json whose files aren't copies of another tx is left alone
+	objects["cosmoshub/validator/7/carol.json"] = []byte("c")
+	if err := reindexQueue(sess, conf, "cosmoshub", "validator"); err == nil || !strings.Contains(err.Error(), "carol.json") {
+		t.Fatalf("got error %v, want the unknown file reported", err)
+	}
+	if _, found := objects["cosmoshub/validator/7/carol.json"]; !found {
+		t.Fatal("carol.json was removed")
+	}
+}
+
+func TestResequenceQueue(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	alice := newTestMember(t, "alice")
+	alice.AWS = bucket
+
+	address, err := bech32.ConvertAndEncode("cosmos", make([]byte, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key sent a tx out-of-band, so the on-chain sequence is 5
+	srv := newTestServer(t, http.StatusOK, `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"`+address+`","account_number":"12","sequence":"5"}}`)
+	chain := Chain{Name: "cosmoshub", Prefix: "cosmos", ID: "cosmoshub-4", REST: srv.URL}
+	key := Key{Name: "validator", Address: address}
+
+	// txs at 1 and 2 made for sequences 4 and 6, and a signature of the first one
+	for index, sequence := range map[string]int{"1": 4, "2": 6} {
+		txDir := "cosmoshub/validator/" + index
+		unsigned := []byte(`{"tx":` + index + `}`)
+		signData, _ := json.Marshal(SignData{Account: 12, Sequence: sequence, ChainID: "cosmoshub-4"})
+		objects[txDir+"/unsigned.json"] = unsigned
+		objects[txDir+"/signdata.json"] = signData
+		if err := writeManifest(sess, alice, txDir, unsigned, signData); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objects["cosmoshub/validator/1/alice.json"] = []byte(`{"signature":"alice"}`)
+
+	// without a member key, nothing is changed
+	bob := &Config{User: "bob", MemberKey: filepath.Join(t.TempDir(), "missing.key"), Members: alice.Members, AWS: bucket}
+	if err := resequenceQueue(sess, bob, chain, key); err == nil || !strings.Contains(err.Error(), "member key") {
+		t.Fatalf("got error %v, want the missing member key reported", err)
+	}
+	if _, found := objects["cosmoshub/validator/1/alice.json"]; !found {
+		t.Fatal("the queue was changed without a member key")
+	}
+
+	if err := resequenceQueue(sess, alice, chain, key); err != nil {
+		t.Fatal(err)
+	}
+	for index, want := range []int{5, 6} {
+		txDir := fmt.Sprintf("cosmoshub/validator/%d", index)
+		var signData SignData
+		if err := json.Unmarshal(objects[txDir+"/signdata.json"], &signData); err != nil || signData.Sequence != want {
+			t.Fatalf("got sign data %+v and %v in %s, want sequence %d", signData, err, txDir, want)
+		}
+		if err := verifyManifest(sess, alice, txDir, objects[txDir+"/unsigned.json"], objects[txDir+"/signdata.json"]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the signature made for the old sequence is moved aside to the archive
+	if _, found := objects["cosmoshub/validator/0/alice.json"]; found {
+		t.Fatal("the signature for the old sequence is still pending")
+	}
+	if len(objectNames(objects, "archive/cosmoshub/validator/")) == 0 {
+		t.Fatal("the signature for the old sequence was not archived")
+	}
+}
@@ -124,7 +124,7 @@ func cmdRawCat(cobraCmd *cobra.Command, args []string) error {
 					return err
 				}
 				fmt.Printf("\n|------------| %s |------------|", file)
-				fmt.Println("\n")
+				fmt.Print("\n\n")
 				fmt.Println(string(b))
 				os.Remove(f.Name())
 			}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+// base url of the chain-registry (https://github.com/cosmos/chain-registry)
+var defaultRegistryURL = "https://raw.githubusercontent.com/cosmos/chain-registry/master"
+
+// directory of the local chain-registry: the one set in the config,
+// or ~/.multisig/registry which is populated by `multisig registry sync`
+func registryDir(conf *Config) (string, error) {
+	if conf.Registry != "" {
+		return expandHome(conf.Registry)
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, defaultRegistryDir), nil
+}
+
+// loadChainInfo returns the registry information for a chain, from the local
+// registry if it is there, otherwise from the chain-registry over http
+func loadChainInfo(conf *Config, chainName string) (ChainInfo, error) {
+	dir, err := registryDir(conf)
+	if err != nil {
+		return ChainInfo{}, err
+	}
+
+	chain, err := fetchChainInfo(dir, chainName)
+	if err == nil {
+		return chain, nil
+	}
+
+	return fetchChainInfo(defaultRegistryURL, chainName)
+}
+
+// fetchChainInfo reads <registry>/<chainName>/chain.json, where registry is either
+// the base url of the chain-registry or the path to a local clone of it
+func fetchChainInfo(registry, chainName string) (ChainInfo, error) {
+	chain := ChainInfo{}
+
+	body, err := readChainJSON(registry, chainName)
+	if err != nil {
+		return chain, err
+	}
+
+	if err := json.Unmarshal(body, &chain); err != nil {
+		return chain, fmt.Errorf("cannot parse chain.json for %s: %s", chainName, err)
+	}
+	return chain, nil
+}
+
+// read the raw chain.json of a chain from the registry
+func readChainJSON(registry, chainName string) ([]byte, error) {
+	return readRegistryFile(registry, chainName, "chain.json")
+}
+
+// read a raw file of a chain, eg. chain.json or assetlist.json, from the registry
+func readRegistryFile(registry, chainName, name string) ([]byte, error) {
+	if isURL(registry) {
+		return fetchRegistryFile(strings.TrimSuffix(registry, "/") + "/" + chainName + "/" + name)
+	}
+	return ioutil.ReadFile(filepath.Join(registry, chainName, name))
+}
+
+// loadAssetList returns the assets of a chain, from the local registry
+// if they are there, otherwise from the chain-registry over http
+func loadAssetList(conf *Config, chainName string) (AssetList, error) {
+	assets := AssetList{}
+
+	dir, err := registryDir(conf)
+	if err != nil {
+		return assets, err
+	}
+	body, err := readRegistryFile(dir, chainName, "assetlist.json")
+	if err != nil {
+		body, err = readRegistryFile(defaultRegistryURL, chainName, "assetlist.json")
+		if err != nil {
+			return assets, err
+		}
+	}
+
+	if err := json.Unmarshal(body, &assets); err != nil {
+		return assets, fmt.Errorf("cannot parse assetlist.json for %s: %s", chainName, err)
+	}
+	return assets, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// fetch a file from the chain-registry over http
+func fetchRegistryFile(url string) ([]byte, error) {
+	res, err := NewHttpClient().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s returned %s", url, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// chainFromRegistry builds a chain config entry from the registry information
+func chainFromRegistry(name string, info ChainInfo) Chain {
+	chain := Chain{
+		Name:   name,
+		Binary: info.DaemonName,
+		Prefix: info.Bech32Prefix,
+		ID:     info.ChainID,
+	}
+
+	// Assumption that the first fee token is the one used for paying the fees
+	if len(info.Fees.FeeTokens) > 0 {
+		feeToken := info.Fees.FeeTokens[0]
+		chain.Denom = feeToken.Denom
+
+		gasPrice := feeToken.AverageGasPrice
+		if gasPrice == 0 {
+			gasPrice = feeToken.LowGasPrice
+		}
+		if gasPrice == 0 {
+			gasPrice = feeToken.FixedMinGasPrice
+		}
+		if gasPrice > 0 {
+			chain.GasPrice = strconv.FormatFloat(gasPrice, 'f', -1, 64) + feeToken.Denom
+		}
+	}
+
+	if len(info.Apis.RPC) > 0 {
+		chain.RPC = info.Apis.RPC[0].Address
+	}
+	if len(info.Apis.REST) > 0 {
+		chain.REST = info.Apis.REST[0].Address
+	}
+	if len(info.Apis.GRPC) > 0 {
+		chain.GRPC = info.Apis.GRPC[0].Address
+	}
+
+	return chain
+}
+
+// add a [[chains]] entry to the config file, populated from the chain-registry
+func cmdConfigAddChain(cmd *cobra.Command, args []string) error {
+	registryName := args[0]
+
+	filename, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(filename)
+	if err != nil {
+		return err
+	}
+
+	var info ChainInfo
+	if flagRegistry != "" {
+		info, err = fetchChainInfo(flagRegistry, registryName)
+	} else {
+		info, err = loadChainInfo(conf, registryName)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot find %s in the chain registry: %s", registryName, err)
+	}
+
+	name := registryName
+	if flagChainName != "" {
+		name = flagChainName
+	}
+	if _, found := conf.GetChain(name); found {
+		return fmt.Errorf("chain %s already exists in %s, use --name to add it under a different name", name, filename)
+	}
+
+	chain := chainFromRegistry(name, info)
+	if chain.Binary == "" {
+		fmt.Printf("WARNING: the registry has no daemon_name for %s, please set the binary manually\n", registryName)
+	}
+
+	// encode only the new chain so the rest of the file (and its comments) is left untouched
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n# added from the chain-registry (%s)\n", registryName)
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Chains []Chain `toml:"chains"`
+	}{[]Chain{chain}}); err != nil {
+		return err
+	}
+	for _, apis := range []struct {
+		name      string
+		endpoints []Endpoint
+	}{{"rpc", info.Apis.RPC}, {"rest", info.Apis.REST}, {"grpc", info.Apis.GRPC}} {
+		if len(apis.endpoints) > 1 {
+			fmt.Fprintf(&buf, "# other public %s endpoints:\n", apis.name)
+			for _, endpoint := range apis.endpoints[1:] {
+				fmt.Fprintf(&buf, "#   %s\n", endpoint.Address)
+			}
+		}
+	}
+
+	fmt.Print(buf.String())
+	if flagDryRun {
+		return nil
+	}
+
+	if err := appendToConfig(filename, buf.Bytes()); err != nil {
+		return err
+	}
+
+	fmt.Printf("added chain %s to %s\n", name, filename)
+	return nil
+}
+
+// append to the config file, so the rest of the file (and its comments) is left untouched
+func appendToConfig(filename string, b []byte) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(b)
+	return err
+}
+
+// copy the chain.json and assetlist.json of the given chains (or all chains in the config) into the local registry
+func cmdRegistrySync(cmd *cobra.Command, args []string) error {
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	dir, err := registryDir(conf)
+	if err != nil {
+		return err
+	}
+
+	source := defaultRegistryURL
+	if flagRegistry != "" {
+		source = flagRegistry
+	}
+
+	chainNames := args
+	if len(chainNames) == 0 {
+		for _, chain := range conf.Chains {
+			chainNames = append(chainNames, chain.Name)
+		}
+	}
+
+	failed := 0
+	for _, chainName := range chainNames {
+		body, err := readChainJSON(source, chainName)
+		if err != nil {
+			fmt.Printf("cannot sync %s: %s\n", chainName, err)
+			failed++
+			continue
+		}
+
+		// make sure its a valid chain.json before caching it
+		var info ChainInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			fmt.Printf("cannot sync %s: invalid chain.json: %s\n", chainName, err)
+			failed++
+			continue
+		}
+
+		chainDir := filepath.Join(dir, chainName)
+		if err := os.MkdirAll(chainDir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(chainDir, "chain.json"), body, 0644); err != nil {
+			return err
+		}
+
+		// the assets are only used to show amounts in display units, so they are optional
+		assets, err := readRegistryFile(source, chainName, "assetlist.json")
+		if err == nil && json.Valid(assets) {
+			if err := os.WriteFile(filepath.Join(chainDir, "assetlist.json"), assets, 0644); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("synced %s to %s\n", chainName, chainDir)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to sync %d of %d chains", failed, len(chainNames))
+	}
+	return nil
+}
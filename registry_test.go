@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestChainFromRegistry(t *testing.T) {
+	info := ChainInfo{
+		ChainID:      "cosmoshub-4",
+		Bech32Prefix: "cosmos",
+		DaemonName:   "gaiad",
+		Apis: Apis{
+			RPC:  []Endpoint{{Address: "https://rpc.one"}, {Address: "https://rpc.two"}},
+			REST: []Endpoint{{Address: "https://rest.one"}},
+		},
+	}
+	cases := []struct {
+		name         string
+		feeTokens    []FeeTokens
+		wantDenom    string
+		wantGasPrice string
+	}{
+		{name: "no fee tokens"},
+		{name: "average gas price", feeTokens: []FeeTokens{{Denom: "uatom", FixedMinGasPrice: 0.001, LowGasPrice: 0.01, AverageGasPrice: 0.025}, {Denom: "ibc/ABC", AverageGasPrice: 1}}, wantDenom: "uatom", wantGasPrice: "0.025uatom"},
+		{name: "low gas price", feeTokens: []FeeTokens{{Denom: "uosmo", LowGasPrice: 0.0025}}, wantDenom: "uosmo", wantGasPrice: "0.0025uosmo"},
+		{name: "fixed min gas price", feeTokens: []FeeTokens{{Denom: "ujuno", FixedMinGasPrice: 0.075}}, wantDenom: "ujuno", wantGasPrice: "0.075ujuno"},
+		{name: "no gas price", feeTokens: []FeeTokens{{Denom: "aevmos"}}, wantDenom: "aevmos"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			info.Fees.FeeTokens = tc.feeTokens
+			chain := chainFromRegistry("hub", info)
+			want := Chain{
+				Name: "hub", Binary: "gaiad", Prefix: "cosmos", ID: "cosmoshub-4",
+				RPC: "https://rpc.one", REST: "https://rest.one", Denom: tc.wantDenom, GasPrice: tc.wantGasPrice,
+			}
+			if chain != want {
+				t.Fatalf("got %+v, want %+v", chain, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+)
+
+// RewardEntry is an unclaimed amount of a denom for a key on a chain: either its
+// delegator rewards, summed over all its validators, or the commission of its validator
+type RewardEntry struct {
+	Chain     string `json:"chain"`
+	Key       string `json:"key"`
+	Address   string `json:"address"`
+	Type      string `json:"type"` // rewards or commission
+	Validator string `json:"validator,omitempty"`
+	Denom     string `json:"denom"`
+	Amount    string `json:"amount"`
+	Display   string `json:"display,omitempty"`
+}
+
+// response of the delegator rewards query
+type delegatorRewards struct {
+	Rewards []struct {
+		ValidatorAddress string        `json:"validator_address"`
+		Reward           []sdk.DecCoin `json:"reward"`
+	} `json:"rewards"`
+	Total []sdk.DecCoin `json:"total"`
+}
+
+// query the unclaimed delegator rewards of an address, summed over all its validators
+func queryRewards(client *ChainClient, address string) (sdk.DecCoins, error) {
+	var rewards delegatorRewards
+	if err := client.query("/cosmos/distribution/v1beta1/delegators/"+address+"/rewards", &rewards); err != nil {
+		return nil, err
+	}
+	return sdk.NewDecCoins(rewards.Total...), nil
+}
+
+// query the unclaimed commission of a validator, which is empty if the address isn't one
+func queryCommission(client *ChainClient, valAddress string) (sdk.DecCoins, error) {
+	var res struct {
+		Commission struct {
+			Commission []sdk.DecCoin `json:"commission"`
+		} `json:"commission"`
+	}
+	if err := client.query("/cosmos/distribution/v1beta1/validators/"+valAddress+"/commission", &res); err != nil {
+		return nil, err
+	}
+	return sdk.NewDecCoins(res.Commission.Commission...), nil
+}
+
+// the exponent and name of the display unit of a denom, from the assets of its chain
+func displayUnit(assets AssetList, denom string) (int, string, bool) {
+	for _, asset := range assets.Assets {
+		if asset.Base != denom {
+			continue
+		}
+		for _, unit := range asset.DenomUnits {
+			if unit.Denom == asset.Display {
+				return unit.Exponent, unit.Denom, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// an amount in the display unit of its denom, eg. 1.5atom for 1500000uatom, or empty if unknown
+func displayAmount(assets AssetList, coin sdk.DecCoin) string {
+	exponent, unit, found := displayUnit(assets, coin.Denom)
+	if !found || unit == coin.Denom {
+		return ""
+	}
+	amount := coin.Amount.Quo(sdk.NewDec(10).Power(uint64(exponent))).String()
+	// at most 6 decimals, without trailing zeros
+	if i := strings.Index(amount, "."); i >= 0 && len(amount) > i+7 {
+		amount = amount[:i+7]
+	}
+	return fmt.Sprintf("%s%s", strings.TrimRight(strings.TrimRight(amount, "0"), "."), unit)
+}
+
+// the minimum amount of denom to claim, from --min in either the denom or its display unit
+func minClaimAmount(assets AssetList, mins sdk.DecCoins, denom string) (sdk.Dec, bool) {
+	for _, min := range mins {
+		if min.Denom == denom {
+			return min.Amount, true
+		}
+		if exponent, unit, found := displayUnit(assets, denom); found && unit == min.Denom {
+			return min.Amount.Mul(sdk.NewDec(10).Power(uint64(exponent))), true
+		}
+	}
+	return sdk.Dec{}, false
+}
+
+// whether an unclaimed amount should be claimed: above its --min, or any amount of the fee denom without --min
+func shouldClaim(conf *Config, assets AssetList, mins sdk.DecCoins, chainName string, coin sdk.DecCoin) bool {
+	if len(mins) == 0 {
+		denom, err := getDenom(conf, chainName)
+		return err == nil && coin.Denom == denom && coin.Amount.IsPositive()
+	}
+	min, found := minClaimAmount(assets, mins, coin.Denom)
+	return found && coin.Amount.GTE(min)
+}
+
+// show the unclaimed rewards and commission of every key on every chain,
+// and optionally push the txs to claim those above --min
+func cmdRewards(cmd *cobra.Command, args []string) error {
+	if flagOutput != "table" && flagOutput != "json" {
+		return fmt.Errorf("invalid --output %q, must be table or json", flagOutput)
+	}
+	mins, err := sdk.ParseDecCoins(strings.Join(flagMin, ","))
+	if err != nil {
+		return fmt.Errorf("invalid --min %s, e.g. 10atom,5000000uosmo: %s", strings.Join(flagMin, ","), err)
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	entries := []RewardEntry{}
+	toWithdraw := [][2]string{}
+	toClaim := [][3]string{}
+	for _, chain := range conf.Chains {
+		if err := requireREST(chain); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s: %s\n", chain.Name, err)
+			continue
+		}
+		client := newChainClient(chain)
+		// amounts are shown in denoms only if the chain has no asset list
+		assets, _ := loadAssetList(conf, chain.Name)
+
+		for _, key := range conf.Keys {
+			address, err := bech32ify(key.Address, chain.Prefix)
+			if err != nil {
+				return err
+			}
+			valAddress, err := bech32ify(key.Address, chain.Prefix+"valoper")
+			if err != nil {
+				return err
+			}
+
+			rewards, err := queryRewards(client, address)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: cannot query the rewards of %s on %s: %s\n", key.Name, chain.Name, err)
+			}
+			withdraw := false
+			for _, coin := range rewards {
+				entries = append(entries, RewardEntry{
+					Chain: chain.Name, Key: key.Name, Address: address, Type: "rewards",
+					Denom: coin.Denom, Amount: coin.Amount.String(), Display: displayAmount(assets, coin),
+				})
+				withdraw = withdraw || shouldClaim(conf, assets, mins, chain.Name, coin)
+			}
+			if withdraw {
+				toWithdraw = append(toWithdraw, [2]string{chain.Name, key.Name})
+			}
+
+			commission, err := queryCommission(client, valAddress)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: cannot query the commission of %s on %s: %s\n", key.Name, chain.Name, err)
+			}
+			claim := false
+			for _, coin := range commission {
+				entries = append(entries, RewardEntry{
+					Chain: chain.Name, Key: key.Name, Address: address, Type: "commission", Validator: valAddress,
+					Denom: coin.Denom, Amount: coin.Amount.String(), Display: displayAmount(assets, coin),
+				})
+				claim = claim || shouldClaim(conf, assets, mins, chain.Name, coin)
+			}
+			if claim {
+				toClaim = append(toClaim, [3]string{chain.Name, key.Name, valAddress})
+			}
+		}
+	}
+
+	if flagOutput == "json" {
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if len(entries) == 0 {
+		fmt.Println("no unclaimed rewards")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHAIN/KEY\tTYPE\tAMOUNT\tDISPLAY")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s/%s\t%s\t%s%s\t%s\n", entry.Chain, entry.Key, entry.Type, entry.Amount, entry.Denom, orDash(entry.Display))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if !flagPush {
+		return nil
+	}
+	chainNames := []string{}
+	for _, pair := range toWithdraw {
+		chainNames = append(chainNames, pair[0])
+	}
+	for _, claim := range toClaim {
+		chainNames = append(chainNames, claim[0])
+	}
+	if err := checkPushFees(cmd, conf, chainNames); err != nil {
+		return err
+	}
+	for _, pair := range toWithdraw {
+		if err := pushQueued(pair[0], pair[1], "withdraw rewards", func(opts pushOptions) error {
+			return withdrawTx(cmd, []string{pair[0], pair[1]}, opts)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, claim := range toClaim {
+		if err := pushQueued(claim[0], claim[1], "claim validator commission", func(opts pushOptions) error {
+			return claimValidatorTx(cmd, []string{claim[0], claim[1], claim[2]}, opts)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
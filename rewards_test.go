@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var testAssets = AssetList{Assets: []Asset{
+	{Base: "uatom", Display: "atom", DenomUnits: []DenomUnit{{Denom: "uatom", Exponent: 0}, {Denom: "atom", Exponent: 6}}},
+	{Base: "aevmos", Display: "evmos", DenomUnits: []DenomUnit{{Denom: "aevmos", Exponent: 0}, {Denom: "evmos", Exponent: 18}}},
+	{Base: "ufoo", Display: "ufoo", DenomUnits: []DenomUnit{{Denom: "ufoo", Exponent: 0}}},
+}}
+
+func TestDisplayAmount(t *testing.T) {
+	cases := []struct {
+		coin string
+		want string
+	}{
+		{coin: "1500000uatom", want: "1.5atom"},
+		{coin: "1000000uatom", want: "1atom"},
+		{coin: "1.234567891uatom", want: "0.000001atom"},
+		{coin: "0uatom", want: "0atom"},
+		{coin: "123456789000000000000aevmos", want: "123.456789evmos"},
+		{coin: "5ufoo", want: ""},
+		{coin: "5ubar", want: ""},
+	}
+	for _, tc := range cases {
+		coin, err := sdk.ParseDecCoin(tc.coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := displayAmount(testAssets, coin); got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.coin, got, tc.want)
+		}
+	}
+}
+
+func TestMinClaimAmount(t *testing.T) {
+	cases := []struct {
+		mins      string
+		denom     string
+		want      string
+		wantFound bool
+	}{
+		{mins: "5000uatom", denom: "uatom", want: "5000", wantFound: true},
+		{mins: "10atom", denom: "uatom", want: "10000000", wantFound: true},
+		{mins: "0.5evmos,10atom", denom: "aevmos", want: "500000000000000000", wantFound: true},
+		{mins: "10atom", denom: "uosmo"},
+		{mins: "10osmo", denom: "ubar"},
+	}
+	for _, tc := range cases {
+		mins, err := sdk.ParseDecCoins(tc.mins)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, found := minClaimAmount(testAssets, mins, tc.denom)
+		if found != tc.wantFound {
+			t.Fatalf("%s for %s: got found %v, want %v", tc.mins, tc.denom, found, tc.wantFound)
+		}
+		if found && !got.Equal(sdk.MustNewDecFromStr(tc.want)) {
+			t.Fatalf("%s for %s: got %s, want %s", tc.mins, tc.denom, got, tc.want)
+		}
+	}
+}
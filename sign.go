@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Signer is our local key to sign the txs of a multisig key with on a chain,
+// for binaries or chains which keep it in a different keystore
+type Signer struct {
+	Key            string `toml:"key,omitempty"`            // name of the multisig key, empty for all keys
+	Chain          string `toml:"chain,omitempty"`          // name of the chain, empty for all chains
+	Name           string `toml:"name"`                     // name of the local signing key, like --from
+	Home           string `toml:"home,omitempty"`           // home of the binary holding the keystore, like --home
+	KeyringBackend string `toml:"keyringbackend,omitempty"` // keyring backend of the keystore, defaults to the global one
+}
+
+// find the signer for a key on a chain among the [[signers]], the most specific one winning:
+// the one for that key and chain, then the one for the key on all chains, then the one for
+// all keys on the chain, then the one for all keys and chains
+func (c *Config) GetSigner(keyName, chainName string) (Signer, bool) {
+	best, bestScore := Signer{}, -1
+	for _, signer := range c.Signers {
+		if (signer.Key != "" && signer.Key != keyName) || (signer.Chain != "" && signer.Chain != chainName) {
+			continue
+		}
+		score := 0
+		if signer.Key != "" {
+			score += 2
+		}
+		if signer.Chain != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = signer, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// our local signer for txs of key on chain: the [[signers]] of the config (see GetSigner),
+// with --from overriding the name of the key
+func localSigner(conf *Config, chain Chain, key Key) (Signer, error) {
+	signer, _ := conf.GetSigner(key.Name, chain.Name)
+	if flagFrom != "" {
+		signer.Name = flagFrom
+	}
+	if signer.Name == "" {
+		return signer, fmt.Errorf("no local key to sign %s txs with on %s, use --from or add it to the [[signers]] of the config", key.Name, chain.Name)
+	}
+	return signer, nil
+}
+
+// the home and keyring backend of the keystore holding a signer's key. The home is --home, then the home
+// of the signer, then the home of the chain. The keyring backend is the one of the signer, then the one
+// of the chain, then the global one
+func signerKeystore(conf *Config, chain Chain, signer Signer) (string, string, error) {
+	backend := conf.GetKeyringBackend(chain)
+	if signer.KeyringBackend != "" {
+		backend = signer.KeyringBackend
+	}
+	home, err := chainHome(chain)
+	if err != nil {
+		return "", "", err
+	}
+	if flagHomePath == "" && signer.Home != "" {
+		home, err = expandHome(signer.Home)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return home, backend, nil
+}
+
+// walk all the pending txs we haven't signed yet, and sign those we confirm
+func signAll(sess *session.Session, conf *Config) error {
+	pairs, err := pendingPairs(sess, conf)
+	if err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	signed, skipped := 0, 0
+	for _, pair := range pairs {
+		chain, _ := conf.GetChain(pair[0])
+		key, _ := conf.GetKey(pair[1])
+
+		queue, err := listQueue(sess, conf, chain.Name, key.Name)
+		if err != nil {
+			return err
+		}
+		for _, tx := range queue {
+			txDir := filepath.Join(chain.Name, key.Name, fmt.Sprintf("%d", tx.Index))
+			if contains(tx.Files, fmt.Sprintf("%s.json", conf.User)) {
+				continue
+			}
+
+			signer, err := localSigner(conf, chain, key)
+			if err != nil {
+				fmt.Printf("skipping %s: %s\n", txDir, err)
+				skipped++
+				continue
+			}
+
+			signData, err := fetchSignData(sess, conf, txDir)
+			if err != nil {
+				return err
+			}
+			if signData.Unordered {
+				if err := checkTimeout(signData); err != nil {
+					fmt.Printf("skipping %s: %s\n", txDir, err)
+					skipped++
+					continue
+				}
+			}
+			unsignedBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, unsignedJSON)
+			if err != nil {
+				return err
+			}
+
+			sep := "----------------------------------------"
+			fmt.Println(sep)
+			fmt.Printf("%s\n", txDir)
+			fmt.Printf("description: %s\n", signData.Description)
+			fmt.Printf("messages:    %s\n", strings.Join(messageTypes(unsignedBytes), ","))
+			if signData.Unordered {
+				fmt.Printf("unordered until %s\n", signData.TimeoutTimestamp)
+			} else {
+				fmt.Printf("sequence:    %d\n", signData.Sequence)
+			}
+			fmt.Printf("signed by:   %s\n", joinOrDash(signatureNames(tx.Files)))
+			fmt.Println(sep)
+
+			if !promptYesNo(in, fmt.Sprintf("sign %s with %s?", txDir, signer.Name)) {
+				skipped++
+				continue
+			}
+			if err := signTx(sess, conf, chain, key, tx.Index, signer); err != nil {
+				return err
+			}
+			signed++
+		}
+	}
+
+	fmt.Printf("signed %d txs, skipped %d\n", signed, skipped)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestGetSigner(t *testing.T) {
+	conf := &Config{Signers: []Signer{
+		{Name: "everywhere"},
+		{Chain: "osmosis", Name: "on-osmosis"},
+		{Key: "validator", Name: "validator-everywhere"},
+		{Key: "validator", Chain: "osmosis", Name: "validator-on-osmosis"},
+		{Key: "treasury", Chain: "cosmoshub", Name: "treasury-on-cosmoshub"},
+	}}
+	cases := []struct {
+		key, chain string
+		want       string
+	}{
+		{key: "validator", chain: "osmosis", want: "validator-on-osmosis"},
+		{key: "validator", chain: "cosmoshub", want: "validator-everywhere"},
+		{key: "treasury", chain: "osmosis", want: "on-osmosis"},
+		{key: "treasury", chain: "cosmoshub", want: "treasury-on-cosmoshub"},
+		{key: "treasury", chain: "juno", want: "everywhere"},
+	}
+	for _, tc := range cases {
+		signer, found := conf.GetSigner(tc.key, tc.chain)
+		if !found || signer.Name != tc.want {
+			t.Fatalf("%s on %s: got %q (found %v), want %q", tc.key, tc.chain, signer.Name, found, tc.want)
+		}
+	}
+
+	// the order of the entries doesn't matter
+	reversed := &Config{}
+	for i := len(conf.Signers) - 1; i >= 0; i-- {
+		reversed.Signers = append(reversed.Signers, conf.Signers[i])
+	}
+	for _, tc := range cases {
+		if signer, _ := reversed.GetSigner(tc.key, tc.chain); signer.Name != tc.want {
+			t.Fatalf("%s on %s with reversed signers: got %q, want %q", tc.key, tc.chain, signer.Name, tc.want)
+		}
+	}
+
+	if _, found := (&Config{Signers: []Signer{{Key: "validator", Name: "v"}}}).GetSigner("treasury", "osmosis"); found {
+		t.Fatal("expected no signer for another key")
+	}
+}
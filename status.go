@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// TxStatus summarizes a pending tx for `multisig status`
+type TxStatus struct {
+	Chain       string   `json:"chain"`
+	Key         string   `json:"key"`
+	Index       int      `json:"index"`
+	Description string   `json:"description"`
+	Messages    []string `json:"messages"`
+	Sequence    int      `json:"sequence"`
+	Unordered   bool     `json:"unordered,omitempty"`
+	Signed      []string `json:"signed"`
+	Missing     []string `json:"missing,omitempty"` // users who haven't signed yet, see missingSigners
+	Threshold   int      `json:"threshold"`
+	Ready       bool     `json:"ready"`
+	Pushed      string   `json:"pushed,omitempty"`
+	Age         string   `json:"age,omitempty"`
+}
+
+// show the pending txs of every chain/key pair, or of one chain/key pair
+func cmdStatus(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		return fmt.Errorf("must specify both a chain name and a key name, or neither")
+	}
+	if flagOutput != "table" && flagOutput != "json" {
+		return fmt.Errorf("invalid --output %q, must be table or json", flagOutput)
+	}
+
+	conf, err := loadConfig(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	sess := awsSession(conf.AWS)
+
+	pairs := [][2]string{}
+	if len(args) == 2 {
+		pairs = append(pairs, [2]string{args[0], args[1]})
+	} else {
+		pairs, err = pendingPairs(sess, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	records, err := fetchMemberRecords(sess, conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: cannot tell which members still need to sign: %s\n", err)
+	}
+
+	statuses := []TxStatus{}
+	for _, pair := range pairs {
+		key, found := conf.GetKey(pair[1])
+		if !found {
+			return fmt.Errorf("key %s not found in config", pair[1])
+		}
+		var multisig *MultisigPubKey
+		chain, found := conf.GetChain(pair[0])
+		if !found && len(args) == 2 {
+			return fmt.Errorf("chain %s not found in config", pair[0])
+		} else if !found {
+			fmt.Fprintf(os.Stderr, "WARNING: chain %s not found in config, using a threshold of %d\n", pair[0], key.GetThreshold())
+		} else if multisig, err = lookupMultisig(conf, chain, key); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: cannot read the multisig of %s on %s, using a threshold of %d: %s\n", key.Name, chain.Name, key.GetThreshold(), err)
+		}
+
+		queue, err := listQueue(sess, conf, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		known := []string{}
+		for _, tx := range queue {
+			for _, name := range signatureNames(tx.Files) {
+				if !contains(known, name) {
+					known = append(known, name)
+				}
+			}
+		}
+		for _, tx := range queue {
+			txDir := filepath.Join(pair[0], pair[1], fmt.Sprintf("%d", tx.Index))
+			signData, err := fetchSignData(sess, conf, txDir)
+			if err != nil {
+				return err
+			}
+			unsignedBytes, err := awsDownloadBytes(sess, conf.AWS, txDir, unsignedJSON)
+			if err != nil {
+				return err
+			}
+
+			status := TxStatus{
+				Chain:       pair[0],
+				Key:         pair[1],
+				Index:       tx.Index,
+				Description: signData.Description,
+				Messages:    messageTypes(unsignedBytes),
+				Sequence:    signData.Sequence,
+				Unordered:   signData.Unordered,
+				Threshold:   multisigThreshold(key, multisig),
+				Pushed:      signData.Pushed,
+			}
+			status.Signed = signatureNames(tx.Files)
+			status.Missing = missingSigners(status.Signed, multisig, records, conf.Members, known)
+			status.Ready = len(status.Signed) >= status.Threshold
+
+			// txs pushed before the sign data recorded it, use the time of the upload
+			pushed, err := time.Parse(time.RFC3339, status.Pushed)
+			if err != nil && !tx.Modified.IsZero() {
+				pushed = tx.Modified
+				status.Pushed = pushed.UTC().Format(time.RFC3339)
+				err = nil
+			}
+			if err == nil {
+				status.Age = formatAge(time.Since(pushed))
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	if flagOutput == "json" {
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("no pending txs")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAIN/KEY\tINDEX\tDESCRIPTION\tMESSAGES\tSEQUENCE\tSIGNED\tMISSING\tREADY\tAGE")
+	for _, status := range statuses {
+		sequence := fmt.Sprint(status.Sequence)
+		if status.Unordered {
+			sequence = "unordered"
+		}
+		ready := "no"
+		if status.Ready {
+			ready = "yes"
+		}
+		fmt.Fprintf(w, "%s/%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s (%d/%d)\t%s\n",
+			status.Chain, status.Key, status.Index, status.Description, strings.Join(status.Messages, ","),
+			sequence, joinOrDash(status.Signed), joinOrDash(status.Missing), ready, len(status.Signed), status.Threshold, status.Age)
+	}
+	return w.Flush()
+}
+
+// the multisig public key of a key on a chain, from its account if the chain has a rest endpoint,
+// else from the keystore of the chain's binary. Nothing is printed, so status can output json
+func lookupMultisig(conf *Config, chain Chain, key Key) (*MultisigPubKey, error) {
+	if chain.REST != "" && key.Address != "" {
+		if m, err := queryChainMultisig(chain, key); err == nil {
+			return m, nil
+		}
+	}
+	ksArgs, err := keystoreArgs(conf, chain)
+	if err != nil {
+		return nil, err
+	}
+	cmdArgs := append([]string{"keys", "show", keyLocalName(key), "--output", "json"}, ksArgs...)
+	b, err := exec.Command(chain.Binary, cmdArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s from the %s keystore: %s", keyLocalName(key), chain.Binary, err)
+	}
+	return parseMultisigPubKey(b)
+}
+
+// the number of signatures needed to broadcast: the threshold of the multisig if it could be read,
+// else the threshold of the config, which defaults to 2
+func multisigThreshold(key Key, multisig *MultisigPubKey) int {
+	if multisig != nil && multisig.Threshold > 0 {
+		return multisig.Threshold
+	}
+	return key.GetThreshold()
+}
+
+// the registered records of every member, see `multisig members register`
+func fetchMemberRecords(sess *session.Session, conf *Config) ([]*MemberRecord, error) {
+	objects, err := awsListObjects(sess, conf.AWS, membersDir+"/")
+	if err != nil {
+		return nil, err
+	}
+	records := []*MemberRecord{}
+	for _, obj := range objects {
+		name := strings.TrimSuffix(filepath.Base(*obj.Key), ".json")
+		record, err := fetchMemberRecord(sess, conf, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+			continue
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// the users who still need to sign a tx: the registered members holding a key of the multisig,
+// else the [[members]] of the config, else the users who signed other txs of the queue
+func missingSigners(signed []string, multisig *MultisigPubKey, records []*MemberRecord, members []Member, known []string) []string {
+	candidates := []string{}
+	if multisig != nil {
+		for _, record := range records {
+			for _, pubKey := range multisig.PublicKeys {
+				if record.hasPubKey(pubKey) && !contains(candidates, record.Name) {
+					candidates = append(candidates, record.Name)
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		for _, member := range members {
+			candidates = append(candidates, member.Name)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = known
+	}
+
+	missing := []string{}
+	for _, name := range candidates {
+		if !contains(signed, name) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// the users who signed a tx, from the names of its signatures (<user>.json)
+func signatureNames(fileNames []string) []string {
+	names := []string{}
+	for _, f := range signatureFiles(fileNames) {
+		names = append(names, strings.TrimSuffix(f, ".json"))
+	}
+	return names
+}
+
+// summarize the messages of an unsigned tx by their type, eg. MsgVote or 2xMsgSend
+func messageTypes(unsignedBytes []byte) []string {
+	var tx struct {
+		Body struct {
+			Messages []struct {
+				Type string `json:"@type"`
+			} `json:"messages"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(unsignedBytes, &tx); err != nil {
+		return []string{"?"}
+	}
+
+	counts := map[string]int{}
+	types := []string{}
+	for _, msg := range tx.Body.Messages {
+		t := msg.Type[strings.LastIndex(msg.Type, ".")+1:]
+		if counts[t] == 0 {
+			types = append(types, t)
+		}
+		counts[t]++
+	}
+	sort.Strings(types)
+	for i, t := range types {
+		if counts[t] > 1 {
+			types[i] = fmt.Sprintf("%dx%s", counts[t], t)
+		}
+	}
+	return types
+}
+
+// format a duration the way people talk about the age of a tx, eg. 3d4h or 12m
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func joinOrDash(list []string) string {
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLookupMultisigThreshold(t *testing.T) {
+	keys := newTestPubKeys(4)
+	key := Key{Name: "validator", Address: "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"}
+
+	// a 3 of 4 multisig on-chain, without a threshold in the config
+	srv := newTestServer(t, http.StatusOK, `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","pub_key":{"@type":"/cosmos.crypto.multisig.LegacyAminoPubKey","threshold":3,"public_keys":[`+
+		`{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"`+keys[0]+`"},{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"`+keys[1]+`"},`+
+		`{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"`+keys[2]+`"},{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"`+keys[3]+`"}]}}}`)
+	m, err := lookupMultisig(&Config{}, Chain{Name: "cosmoshub", Prefix: "cosmos", REST: srv.URL, Binary: "false"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := multisigThreshold(key, m); got != 3 || len(m.PublicKeys) != 4 {
+		t.Fatalf("got threshold %d and %d keys, want 3 of 4", got, len(m.PublicKeys))
+	}
+
+	// without a rest endpoint the multisig is read from the keystore
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "testd")
+	script := "#!/bin/sh\necho '{\"name\":\"validator\",\"type\":\"multi\",\"pubkey\":\"{\\\"@type\\\":\\\"/cosmos.crypto.multisig.LegacyAminoPubKey\\\",\\\"threshold\\\":4,\\\"public_keys\\\":[]}\"}'\n"
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m, err = lookupMultisig(&Config{}, Chain{Name: "cosmoshub", Binary: binary}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := multisigThreshold(key, m); got != 4 {
+		t.Fatalf("got threshold %d from the keystore, want 4", got)
+	}
+
+	// the config threshold, and then 2, are only used if the multisig can't be read
+	if _, err := lookupMultisig(&Config{}, Chain{Name: "cosmoshub", Binary: "false"}, key); err == nil {
+		t.Fatal("expected an error without a multisig")
+	}
+	if got := multisigThreshold(key, nil); got != defaultThreshold {
+		t.Fatalf("got threshold %d, want the default %d", got, defaultThreshold)
+	}
+	if got := multisigThreshold(Key{Threshold: 3}, nil); got != 3 {
+		t.Fatalf("got threshold %d, want the configured 3", got)
+	}
+}
+
+func TestMissingSigners(t *testing.T) {
+	keys := newTestPubKeys(4)
+	multisig := &MultisigPubKey{Threshold: 3, PublicKeys: keys[:3]}
+	records := []*MemberRecord{
+		{Name: "alice", PubKeys: []string{keys[0]}},
+		{Name: "bob", PubKeys: []string{keys[1]}},
+		{Name: "carol", PubKeys: []string{keys[2]}},
+		{Name: "dave", PubKeys: []string{keys[3]}}, // registered but not in this multisig
+	}
+	members := []Member{{Name: "alice"}, {Name: "erin"}}
+
+	cases := []struct {
+		name     string
+		multisig *MultisigPubKey
+		records  []*MemberRecord
+		members  []Member
+		known    []string
+		want     []string
+	}{
+		{name: "registered holders of the multisig keys", multisig: multisig, records: records, members: members, want: []string{"bob", "carol"}},
+		{name: "no registrations, members of the config", multisig: multisig, members: members, known: []string{"bob"}, want: []string{"erin"}},
+		{name: "unknown multisig, members of the config", records: records, members: members, want: []string{"erin"}},
+		{name: "no members, known signers", known: []string{"alice", "frank"}, want: []string{"frank"}},
+		{name: "nothing known", want: []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := missingSigners([]string{"alice"}, tc.multisig, tc.records, tc.members, tc.known)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// default name of the shared team config in the bucket
+	defaultTeamConfig = "team.toml"
+
+	// local copy of the last team config fetched, used when the bucket can't be reached
+	teamConfigCacheFile = ".team.toml"
+
+	// how old the local copy can be to still be used
+	teamConfigCacheMaxAge = 24 * time.Hour
+
+	// team configs already fetched by this process, by local copy, so they're only fetched once
+	fetchedTeamConfigs = map[string]*TeamConfig{}
+)
+
+// TeamConfig is the canonical definition of the keys and chains, shared by the whole team
+type TeamConfig struct {
+	Keys    []Key    `toml:"keys"`
+	Chains  []Chain  `toml:"chains"`
+	Members []Member `toml:"members,omitempty"` // only parsed to reject it, members are trusted from the local config only
+}
+
+func teamConfigObject(conf *Config) string {
+	if conf.TeamConfig != "" {
+		return conf.TeamConfig
+	}
+	return defaultTeamConfig
+}
+
+// parse a team config, making sure it doesn't hold anything personal
+func parseTeamConfig(b []byte) (*TeamConfig, error) {
+	team := &TeamConfig{}
+	md, err := toml.Decode(string(b), team)
+	if err != nil {
+		return nil, err
+	}
+	migrateNodes(team.Chains)
+	for _, key := range team.Keys {
+		if key.LocalName != "" {
+			return nil, fmt.Errorf("key %s has a localname, which is personal and belongs in the local config", key.Name)
+		}
+	}
+	for _, chain := range team.Chains {
+		if chain.KeyringBackend != "" || chain.Home != "" {
+			return nil, fmt.Errorf("chain %s has a keyringbackend or home, which are personal and belong in the local config", chain.Name)
+		}
+	}
+	// the from of the chains was replaced by the [[signers]], which are personal too
+	for _, key := range md.Undecoded() {
+		if key.String() == "chains.from" || key[0] == "signers" {
+			return nil, fmt.Errorf("the team config has a from or [[signers]], which are personal and belong in the [[signers]] of the local config")
+		}
+	}
+	// anyone who can write to the bucket could otherwise add their own member key
+	if len(team.Members) > 0 {
+		return nil, fmt.Errorf("the team config has [[members]], which are trusted to sign manifests and belong in each local config")
+	}
+	return team, nil
+}
+
+// fetch the team config from the bucket, once per process. If the bucket can't be reached,
+// fall back to the copy cached next to the local config file, unless it's older than teamConfigCacheMaxAge
+func fetchTeamConfig(conf *Config, configFile string) (*TeamConfig, error) {
+	cacheFile := filepath.Join(filepath.Dir(configFile), teamConfigCacheFile)
+	if team, found := fetchedTeamConfigs[cacheFile]; found {
+		return team, nil
+	}
+
+	sess := awsSession(conf.AWS)
+	b, err := awsDownloadBytes(sess, conf.AWS, "", teamConfigObject(conf))
+	if err != nil {
+		info, statErr := os.Stat(cacheFile)
+		if statErr != nil {
+			return nil, fmt.Errorf("cannot fetch team config %s: %s", teamConfigObject(conf), err)
+		}
+		age := time.Since(info.ModTime()).Round(time.Minute)
+		if age > teamConfigCacheMaxAge {
+			return nil, fmt.Errorf("cannot fetch team config %s, and the copy in %s is too old to use (%s): %s", teamConfigObject(conf), cacheFile, age, err)
+		}
+		cached, cacheErr := ioutil.ReadFile(cacheFile)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("cannot fetch team config %s: %s", teamConfigObject(conf), err)
+		}
+		fmt.Fprintf(os.Stderr, "WARNING: cannot fetch team config %s, using the copy cached %s ago in %s: %s\n", teamConfigObject(conf), age, cacheFile, err)
+		b = cached
+	} else if err := os.WriteFile(cacheFile, b, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: cannot cache team config in %s: %s\n", cacheFile, err)
+	}
+
+	team, err := parseTeamConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid team config %s: %s", teamConfigObject(conf), err)
+	}
+	fetchedTeamConfigs[cacheFile] = team
+	return team, nil
+}
+
+// merge the team config into the local one. The keys and chains of the team config
+// take precedence, the local config only provides the personal fields (eg. localname).
+// Keys and chains only in the local config are kept, members only come from the local config
+func mergeTeamConfig(conf *Config, team *TeamConfig) {
+	keys := []Key{}
+	for _, teamKey := range team.Keys {
+		if localKey, found := conf.GetKey(teamKey.Name); found {
+			teamKey = mergeKey(teamKey, localKey)
+		}
+		keys = append(keys, teamKey)
+	}
+	for _, localKey := range conf.Keys {
+		if !hasKey(team.Keys, localKey.Name) {
+			keys = append(keys, localKey)
+		}
+	}
+
+	chains := []Chain{}
+	for _, teamChain := range team.Chains {
+		if localChain, found := conf.GetChain(teamChain.Name); found {
+			teamChain = mergeChain(teamChain, localChain)
+		}
+		chains = append(chains, teamChain)
+	}
+	for _, localChain := range conf.Chains {
+		if !hasChain(team.Chains, localChain.Name) {
+			chains = append(chains, localChain)
+		}
+	}
+
+	conf.Keys = keys
+	conf.Chains = chains
+}
+
+// take the personal fields of a key from the local config
+func mergeKey(team, local Key) Key {
+	if local.LocalName != "" {
+		team.LocalName = local.LocalName
+	}
+	return team
+}
+
+// take the personal fields of a chain from the local config
+func mergeChain(team, local Chain) Chain {
+	if local.RPC != "" {
+		team.RPC = local.RPC
+	}
+	if local.REST != "" {
+		team.REST = local.REST
+	}
+	if local.GRPC != "" {
+		team.GRPC = local.GRPC
+	}
+	if local.KeyringBackend != "" {
+		team.KeyringBackend = local.KeyringBackend
+	}
+	if local.Home != "" {
+		team.Home = local.Home
+	}
+	return team
+}
+
+func hasKey(keys []Key, name string) bool {
+	for _, key := range keys {
+		if key.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasChain(chains []Chain, name string) bool {
+	for _, chain := range chains {
+		if chain.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// download the team config and write it to a file, or stdout
+func cmdConfigPull(cmd *cobra.Command, args []string) error {
+	configFile, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	conf, err := loadLocalConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	sess := awsSession(conf.AWS)
+	b, err := awsDownloadBytes(sess, conf.AWS, "", teamConfigObject(conf))
+	if err != nil {
+		return fmt.Errorf("cannot fetch team config %s: %s", teamConfigObject(conf), err)
+	}
+
+	if len(args) == 0 {
+		fmt.Print(string(b))
+		return nil
+	}
+
+	if err := os.WriteFile(args[0], b, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("pulled %s to %s\n", teamConfigObject(conf), args[0])
+	return nil
+}
+
+// upload a file as the new team config
+func cmdConfigPush(cmd *cobra.Command, args []string) error {
+	teamFile := args[0]
+
+	configFile, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	conf, err := loadLocalConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(teamFile)
+	if err != nil {
+		return err
+	}
+
+	team, err := parseTeamConfig(b)
+	if err != nil {
+		return fmt.Errorf("invalid team config %s: %s", teamFile, err)
+	}
+	seen := map[string]bool{}
+	for _, key := range team.Keys {
+		if seen[key.Name] {
+			return fmt.Errorf("key %s is defined more than once in %s", key.Name, teamFile)
+		}
+		seen[key.Name] = true
+		if _, err := bech32ify(key.Address, "cosmos"); err != nil {
+			return fmt.Errorf("key %s has an invalid bech32 address %q: %s", key.Name, key.Address, err)
+		}
+	}
+	seenChains := map[string]bool{}
+	for _, chain := range team.Chains {
+		if seenChains[chain.Name] {
+			return fmt.Errorf("chain %s is defined more than once in %s", chain.Name, teamFile)
+		}
+		seenChains[chain.Name] = true
+	}
+
+	sess := awsSession(conf.AWS)
+	if err := awsUpload(sess, conf.AWS, "", teamConfigObject(conf), b); err != nil {
+		return err
+	}
+	fmt.Printf("pushed %s to %s with %d keys and %d chains\n", teamFile, teamConfigObject(conf), len(team.Keys), len(team.Chains))
+	return nil
+}
+
+// show the differences between the team config in the bucket and a local file
+// (by default, the keys and chains of the local config file)
+func cmdConfigDiff(cmd *cobra.Command, args []string) error {
+	configFile, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+	conf, err := loadLocalConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	sess := awsSession(conf.AWS)
+	b, err := awsDownloadBytes(sess, conf.AWS, "", teamConfigObject(conf))
+	if err != nil {
+		return fmt.Errorf("cannot fetch team config %s: %s", teamConfigObject(conf), err)
+	}
+	remote, err := parseTeamConfig(b)
+	if err != nil {
+		return fmt.Errorf("invalid team config %s: %s", teamConfigObject(conf), err)
+	}
+
+	local := &TeamConfig{Keys: conf.Keys, Chains: conf.Chains}
+	localName := configFile
+	if len(args) == 1 {
+		localName = args[0]
+		lb, err := ioutil.ReadFile(localName)
+		if err != nil {
+			return err
+		}
+		local = &TeamConfig{}
+		if err := toml.Unmarshal(lb, local); err != nil {
+			return err
+		}
+		migrateNodes(local.Chains)
+	}
+
+	fmt.Printf("--- %s (bucket)\n", teamConfigObject(conf))
+	fmt.Printf("+++ %s\n", localName)
+
+	remoteEntries := map[string]interface{}{}
+	localEntries := map[string]interface{}{}
+	for _, k := range remote.Keys {
+		remoteEntries["key "+k.Name] = k
+	}
+	for _, k := range local.Keys {
+		localEntries["key "+k.Name] = k
+	}
+	for _, c := range remote.Chains {
+		remoteEntries["chain "+c.Name] = c
+	}
+	for _, c := range local.Chains {
+		localEntries["chain "+c.Name] = c
+	}
+
+	names := []string{}
+	for name := range remoteEntries {
+		names = append(names, name)
+	}
+	for name := range localEntries {
+		if _, found := remoteEntries[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	differences := 0
+	for _, name := range names {
+		r, inRemote := remoteEntries[name]
+		l, inLocal := localEntries[name]
+		switch {
+		case !inLocal:
+			fmt.Printf("- %s\n", name)
+			differences++
+		case !inRemote:
+			fmt.Printf("+ %s\n", name)
+			differences++
+		default:
+			for _, d := range diffFields(r, l) {
+				fmt.Printf("~ %s: %s\n", name, d)
+				differences++
+			}
+		}
+	}
+
+	if differences == 0 {
+		fmt.Println("no differences")
+	}
+	return nil
+}
+
+// compare the fields of two config entries of the same type, ignoring personal fields
+func diffFields(a, b interface{}) []string {
+	diffs := []string{}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		switch name {
+		case "localname", "node", "rpc", "rest", "grpc", "keyringbackend", "home":
+			continue
+		}
+		fa, fb := va.Field(i).Interface(), vb.Field(i).Interface()
+		if !reflect.DeepEqual(fa, fb) {
+			diffs = append(diffs, fmt.Sprintf("%s %q -> %q", name, fmt.Sprint(fa), fmt.Sprint(fb)))
+		}
+	}
+	return diffs
+}
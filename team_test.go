@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTeamConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		toml    string
+		wantErr string
+	}{
+		{
+			name: "keys and chains",
+			toml: `
+[[keys]]
+name = "validator"
+address = "cosmos1abc"
+
+[[chains]]
+name = "cosmoshub"
+binary = "gaiad"
+node = "http://localhost:26657"
+`,
+		},
+		{name: "localname", toml: "[[keys]]\nname = \"validator\"\nlocalname = \"mine\"\n", wantErr: "localname"},
+		{name: "chain keyringbackend", toml: "[[chains]]\nname = \"cosmoshub\"\nkeyringbackend = \"test\"\n", wantErr: "keyringbackend"},
+		{name: "chain home", toml: "[[chains]]\nname = \"cosmoshub\"\nhome = \"~/.gaia\"\n", wantErr: "home"},
+		{name: "chain from", toml: "[[chains]]\nname = \"cosmoshub\"\nfrom = \"mine\"\n", wantErr: "[[signers]]"},
+		{name: "signers", toml: "[[signers]]\nname = \"mine\"\n", wantErr: "[[signers]]"},
+		{name: "members", toml: "[[members]]\nname = \"eve\"\npubkey = \"AAAA\"\n", wantErr: "[[members]]"},
+		{name: "invalid toml", toml: "[[keys]\n", wantErr: "expected"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			team, err := parseTeamConfig([]byte(tc.toml))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the deprecated node is read as the rpc endpoint
+			if len(team.Chains) != 1 || team.Chains[0].RPC != "http://localhost:26657" || team.Chains[0].Node != "" {
+				t.Fatalf("got chains %+v", team.Chains)
+			}
+		})
+	}
+}
+
+func TestMergeTeamConfig(t *testing.T) {
+	conf := &Config{
+		Keys: []Key{
+			{Name: "validator", Address: "cosmos1local", LocalName: "my-validator", Threshold: 1},
+			{Name: "personal", Address: "cosmos1personal"},
+		},
+		Chains: []Chain{
+			{Name: "cosmoshub", ID: "local-1", Binary: "evil", RPC: "http://my-node:26657", Home: "~/.gaia-test", KeyringBackend: "test"},
+			{Name: "testnet", ID: "test-1"},
+		},
+		Members: []Member{{Name: "alice", PubKey: "local"}},
+	}
+	team := &TeamConfig{
+		Keys:   []Key{{Name: "validator", Address: "cosmos1team", Threshold: 3}},
+		Chains: []Chain{{Name: "cosmoshub", ID: "cosmoshub-4", Binary: "gaiad", RPC: "https://rpc.team", REST: "https://rest.team"}},
+	}
+	mergeTeamConfig(conf, team)
+
+	// the team config wins, except for the personal fields of the local config
+	wantKeys := []Key{
+		{Name: "validator", Address: "cosmos1team", LocalName: "my-validator", Threshold: 3},
+		{Name: "personal", Address: "cosmos1personal"},
+	}
+	if len(conf.Keys) != len(wantKeys) {
+		t.Fatalf("got keys %+v, want %+v", conf.Keys, wantKeys)
+	}
+	for i, want := range wantKeys {
+		if conf.Keys[i] != want {
+			t.Fatalf("got key %+v, want %+v", conf.Keys[i], want)
+		}
+	}
+	wantChains := []Chain{
+		{Name: "cosmoshub", ID: "cosmoshub-4", Binary: "gaiad", RPC: "http://my-node:26657", REST: "https://rest.team", Home: "~/.gaia-test", KeyringBackend: "test"},
+		{Name: "testnet", ID: "test-1"},
+	}
+	if len(conf.Chains) != len(wantChains) {
+		t.Fatalf("got chains %+v, want %+v", conf.Chains, wantChains)
+	}
+	for i, want := range wantChains {
+		if conf.Chains[i] != want {
+			t.Fatalf("got chain %+v, want %+v", conf.Chains[i], want)
+		}
+	}
+	if len(conf.Members) != 1 || conf.Members[0].PubKey != "local" {
+		t.Fatalf("got members %+v, want the local ones", conf.Members)
+	}
+}
@@ -1,7 +1,7 @@
 #!/bin/bash
 
 # launching gaia and minio
-gaiad start --minimum-gas-prices 0atom &> gaia.log &
+gaiad start --minimum-gas-prices 0atom --api.enable &> gaia.log &
 minio server /data --console-address ":9001" > minio.log &
 
 # waiting for gaia to start (assuming gaia starts way more longer than minio, so no waiting for minio)
@@ -170,125 +170,6 @@ type AccountType struct {
 	Type string `json:"@type"`
 }
 
-type AcctType50 struct {
-	Account struct {
-		Type string `json:"type"`
-	}
-}
-
-// AcctType50Proto handles SDK v0.50+ chains that use proto-JSON encoding
-// where the account uses @type instead of type/value Amino encoding.
-type AcctType50Proto struct {
-	Account struct {
-		Type string `json:"@type"`
-	} `json:"account"`
-}
-
-// BaseAccount50Proto handles the proto-JSON format where fields are directly
-// in the account object (no value wrapper).
-type BaseAccount50Proto struct {
-	Account struct {
-		Type          string `json:"@type"`
-		Address       string `json:"address"`
-		AccountNumber string `json:"account_number"`
-		Sequence      string `json:"sequence"`
-	} `json:"account"`
-}
-
-type BaseAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			Address   string `json:"address"`
-			PublicKey struct {
-				Type  string `json:"type"`
-				Value struct {
-					Threshold string `json:"threshold"`
-					PubKeys   []struct {
-						Type  string `json:"type"`
-						Value string `json:"value"`
-					} `json:"pubkeys"`
-				}
-			}
-			AccountNumber string `json:"account_number"`
-			Sequence      string `json:"sequence"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
-type PeriodicVestingAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			BaseVestingAccount struct {
-				BaseAccount struct {
-					Address       string `json:"address"`
-					AccountNumber string `json:"account_number"`
-					Sequence      string `json:"sequence"`
-				} `json:"base_account"`
-			} `json:"base_vesting_account"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
-type ContinuousVestingAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			BaseVestingAccount struct {
-				BaseAccount struct {
-					Address       string `json:"address"`
-					AccountNumber string `json:"account_number"`
-					Sequence      string `json:"sequence"`
-				} `json:"base_account"`
-			} `json:"base_vesting_account"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
-type DelayedVestingAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			BaseVestingAccount struct {
-				BaseAccount struct {
-					Address       string `json:"address"`
-					AccountNumber string `json:"account_number"`
-					Sequence      string `json:"sequence"`
-				} `json:"base_account"`
-			} `json:"base_vesting_account"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
-type StridePeriodicVestingAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			BaseVestingAccount struct {
-				BaseAccount struct {
-					Address       string `json:"address"`
-					AccountNumber string `json:"account_number"`
-					Sequence      string `json:"sequence"`
-				} `json:"base_account"`
-			} `json:"base_vesting_account"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
-type EthAccount50 struct {
-	Account struct {
-		Type  string `json:"type"`
-		Value struct {
-			BaseAccount struct {
-				Address       string `json:"address"`
-				AccountNumber string `json:"account_number"`
-				Sequence      string `json:"sequence"`
-			} `json:"base_account"`
-		} `json:"value"`
-	} `json:"account"`
-}
-
 type AccountBalance struct {
 	Balances []struct {
 		Denom  string `json:"denom"`
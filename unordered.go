@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// mark an unsigned tx as unordered, expiring at timeoutTimestamp (RFC3339)
+func setUnordered(unsignedTxBytes []byte, timeoutTimestamp string) ([]byte, error) {
+	// keep numbers as they are
+	dec := json.NewDecoder(bytes.NewReader(unsignedTxBytes))
+	dec.UseNumber()
+	var tx map[string]interface{}
+	if err := dec.Decode(&tx); err != nil {
+		return nil, fmt.Errorf("cannot parse the unsigned tx: %s", err)
+	}
+	body, ok := tx["body"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the unsigned tx has no body")
+	}
+	body["unordered"] = true
+	body["timeout_timestamp"] = timeoutTimestamp
+	return json.Marshal(tx)
+}
+
+// check an unordered tx can still be broadcast
+func checkTimeout(signData SignData) error {
+	timeout, err := time.Parse(time.RFC3339, signData.TimeoutTimestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timeout timestamp %q in the sign data", signData.TimeoutTimestamp)
+	}
+	if time.Now().After(timeout) {
+		return fmt.Errorf("the unordered tx expired at %s and can no longer be broadcast", signData.TimeoutTimestamp)
+	}
+	return nil
+}
+
+// count the pending unordered txs of a chain/key pair, which don't use up a sequence
+func countUnordered(sess *session.Session, conf *Config, chainName, keyName string) (int, error) {
+	queue, err := listQueue(sess, conf, chainName, keyName)
+	if err != nil {
+		return 0, err
+	}
+
+	unordered := 0
+	for _, tx := range queue {
+		signData, err := fetchSignData(sess, conf, filepath.Join(chainName, keyName, fmt.Sprintf("%d", tx.Index)))
+		if err != nil {
+			return 0, err
+		}
+		if signData.Unordered {
+			unordered++
+		}
+	}
+	return unordered, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+func TestSetUnordered(t *testing.T) {
+	unsigned := `{"body":{"messages":[{"@type":"/cosmos.bank.v1beta1.MsgSend","amount":[{"denom":"uatom","amount":"1"}]}],"memo":"","timeout_height":"0"},"auth_info":{"fee":{"gas_limit":"300000"}},"big":123456789012345678901234567890}`
+	b, err := setUnordered([]byte(unsigned), "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// numbers are kept as they are instead of going through float64
+	if !strings.Contains(string(b), "123456789012345678901234567890") {
+		t.Fatalf("number changed in %s", b)
+	}
+
+	var tx struct {
+		Body struct {
+			Messages         []json.RawMessage `json:"messages"`
+			Unordered        bool              `json:"unordered"`
+			TimeoutTimestamp string            `json:"timeout_timestamp"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(b, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if !tx.Body.Unordered || tx.Body.TimeoutTimestamp != "2026-01-01T00:00:00Z" || len(tx.Body.Messages) != 1 {
+		t.Fatalf("got body %+v", tx.Body)
+	}
+
+	for _, invalid := range []string{`{"auth_info":{}}`, `{"body":"x"}`, `not json`} {
+		if _, err := setUnordered([]byte(invalid), "2026-01-01T00:00:00Z"); err == nil {
+			t.Fatalf("expected an error for %s", invalid)
+		}
+	}
+}
+
+// a fake binary signing txs: it writes its arguments and the tx to sign next to itself and prints a signature
+func newTestSigningBinary(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + filepath.Join(dir, "args.txt") + "\n" +
+		"cp \"$3\" " + filepath.Join(dir, "tx.json") + "\n" +
+		"echo '{\"signatures\":[{\"sequence\":\"0\"}]}'\n"
+	binary := filepath.Join(dir, "simd")
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return binary, dir
+}
+
+func TestSignUnorderedTx(t *testing.T) {
+	bucket, objects := newTestBucket(t)
+	sess := awsSession(bucket)
+	conf := &Config{User: "alice", KeyringBackend: "test", AWS: bucket}
+	binary, dir := newTestSigningBinary(t)
+
+	address, err := bech32.ConvertAndEncode("cosmos", make([]byte, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := Chain{Name: "cosmoshub", Binary: binary, Prefix: "cosmos", ID: "cosmoshub-4"}
+	key := Key{Name: "validator", Address: address}
+
+	unsigned, err := setUnordered([]byte(`{"body":{"messages":[],"memo":"","timeout_height":"0"},"auth_info":{},"signatures":[]}`), "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signData, _ := json.Marshal(SignData{Account: 12, ChainID: "cosmoshub-4", Unordered: true, TimeoutTimestamp: "2026-01-01T00:00:00Z"})
+	objects["cosmoshub/validator/0/unsigned.json"] = unsigned
+	objects["cosmoshub/validator/0/signdata.json"] = signData
+
+	if err := signTx(sess, conf, chain, key, 0, Signer{Name: "alice-key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// unordered txs are signed in amino-json mode with sequence 0, like all multisig txs
+	args, err := os.ReadFile(filepath.Join(dir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"tx sign ", "--multisig " + address, "--from alice-key", "--account-number 12", "--sequence 0", "--chain-id cosmoshub-4", "--sign-mode amino-json", "--offline", "--keyring-backend test"} {
+		if !strings.Contains(string(args), want) {
+			t.Fatalf("binary called with %q, missing %q", args, want)
+		}
+	}
+	// and the binary gets the body with the unordered flag and timeout as they were pushed
+	signed, err := os.ReadFile(filepath.Join(dir, "tx.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signed) != string(unsigned) {
+		t.Fatalf("binary got %s, want %s", signed, unsigned)
+	}
+	if _, found := objects["cosmoshub/validator/0/alice.json"]; !found {
+		t.Fatal("the signature was not uploaded")
+	}
+}
@@ -4,9 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
 )
 
 // ChainInfo simplified version of the chain information from the registry
@@ -23,20 +20,53 @@ type ChainInfo struct {
 	KeyAlgos     []string `json:"key_algos"`
 	Slip44       int      `json:"slip44"`
 	Fees         Fees     `json:"fees"`
+	Apis         Apis     `json:"apis"`
+}
+
+// AssetList simplified version of the assets of a chain from the registry
+type AssetList struct {
+	ChainName string  `json:"chain_name"`
+	Assets    []Asset `json:"assets"`
+}
+
+type Asset struct {
+	Base       string      `json:"base"`
+	Display    string      `json:"display"`
+	Symbol     string      `json:"symbol"`
+	DenomUnits []DenomUnit `json:"denom_units"`
+}
+
+type DenomUnit struct {
+	Denom    string `json:"denom"`
+	Exponent int    `json:"exponent"`
 }
 
 type FeeTokens struct {
-	Denom            string `json:"denom"`
-	FixedMinGasPrice int    `json:"fixed_min_gas_price"`
+	Denom            string  `json:"denom"`
+	FixedMinGasPrice float64 `json:"fixed_min_gas_price"`
+	LowGasPrice      float64 `json:"low_gas_price"`
+	AverageGasPrice  float64 `json:"average_gas_price"`
+	HighGasPrice     float64 `json:"high_gas_price"`
 }
 type Fees struct {
 	FeeTokens []FeeTokens `json:"fee_tokens"`
 }
 
+type Endpoint struct {
+	Address  string `json:"address"`
+	Provider string `json:"provider"`
+}
+type Apis struct {
+	RPC  []Endpoint `json:"rpc"`
+	REST []Endpoint `json:"rest"`
+	GRPC []Endpoint `json:"grpc"`
+}
+
 // getDenom get the denom to be used in transaction fees
 // This method first will try to retrieve the denom from the configuration '[[chains]] denom'
-// if the deno	m is not available in the configuration then it will try to retrieve it from
-// the chain-registry (https://github.com/cosmos/chain-registry). But in order for the chain
+// if the denom is not available in the configuration then it will try to retrieve it from
+// the local copy of the chain-registry (see `multisig registry sync`) and then from the
+// chain-registry (https://github.com/cosmos/chain-registry). But in order for the chain
 // registry retrieval to work, the chain name in the configuration file has to match the
 // chain_name property in the chain.json. For example
 // https://github.com/cosmos/chain-registry/blob/5ebdb2cf8bf0a6a14d602d4e63fd046f66895cbb/cosmoshub/chain.json#L3
@@ -49,7 +79,7 @@ func getDenom(conf *Config, chainName string) (string, error) {
 			return chain.Denom, nil
 		} else {
 			// Try chain registry
-			denom, err := getDenomFromRegistry(chainName)
+			denom, err := getDenomFromRegistry(conf, chainName)
 			if err != nil {
 				return "", errors.New(fmt.Sprintf("cannot find denom in the config or registry: %s", err))
 			} else {
@@ -59,40 +89,16 @@ func getDenom(conf *Config, chainName string) (string, error) {
 	}
 }
 
-func getDenomFromRegistry(chainName string) (string, error) {
-
-	chain := ChainInfo{}
-	denom := ""
-	url := fmt.Sprintf("https://raw.githubusercontent.com/cosmos/chain-registry/master/%s/chain.json", chainName)
-	method := "GET"
-
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
+func getDenomFromRegistry(conf *Config, chainName string) (string, error) {
+	chain, err := loadChainInfo(conf, chainName)
 	if err != nil {
 		fmt.Println(err)
-		return denom, err
-	}
-	res, err := client.Do(req)
-	if (err != nil) || (res.StatusCode == 404) {
-		fmt.Println(err)
-		return denom, errors.New(fmt.Sprintf("cannot find denom in the chain registry, please ensure the chain name in the configuration file matches the folder name in the registry (https://github.com/cosmos/chain-registry)"))
-	}
-	defer res.Body.Close()
-
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println(err)
-		return denom, err
-	}
-	err = json.Unmarshal(body, &chain)
-	if err != nil {
-		fmt.Println(err)
-		return denom, err
+		return "", errors.New(fmt.Sprintf("cannot find denom in the chain registry, please ensure the chain name in the configuration file matches the folder name in the registry (https://github.com/cosmos/chain-registry)"))
 	}
 
 	// Assumption that the first fee token is the one used for paying
 	// the fees and the fee information is in the registry
-	if chain.Fees.FeeTokens != nil {
+	if len(chain.Fees.FeeTokens) > 0 {
 		if chain.Fees.FeeTokens[0].Denom != "" {
 			return chain.Fees.FeeTokens[0].Denom, nil
 		}
@@ -171,23 +177,13 @@ func parseSdkVersion(version string) (int, int, int, error) {
 	return major, minor, patch, nil
 }
 
-// isSDK050OrGreater checks if the SDK version is 0.50 or greater
-// SDK 0.50+ uses "query auth account" instead of "query account"
-func isSDK050OrGreater(version string) bool {
+// isSDK053OrGreater checks if the SDK version is 0.53 or greater
+// SDK 0.53+ supports unordered transactions
+func isSDK053OrGreater(version string) bool {
 	major, minor, _, err := parseSdkVersion(version)
 	if err != nil {
-		// If parsing fails, fall back to string comparison
-		return strings.Contains(version, "v0.50") || strings.Contains(version, "v0.51") ||
-		       strings.Contains(version, "v0.52") || strings.Contains(version, "v0.53") ||
-		       strings.Contains(version, "v0.54") || strings.Contains(version, "v0.55")
-	}
-
-	if major == 0 && minor >= 50 {
-		return true
-	}
-	if major > 0 {
-		return true
+		return false
 	}
 
-	return false
+	return major > 0 || minor >= 53
 }
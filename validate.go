@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/spf13/cobra"
+)
+
+// name of the object written and deleted to check bucket access
+var validateProbeObject = ".multisig-validate-%s"
+
+// check the config for mistakes that would otherwise only show up mid-way through signing or broadcasting
+func cmdConfigValidate(cmd *cobra.Command, args []string) error {
+	filename, err := configFilePath(flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(filename)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("validating %s\n", filename)
+
+	problems := []string{}
+	problems = append(problems, validateGeneral(conf)...)
+	problems = append(problems, validateKeys(conf)...)
+	problems = append(problems, validateChains(conf)...)
+	if !flagOffline {
+		problems = append(problems, validateBucket(conf)...)
+	}
+
+	sep := "----------------------------------------"
+	fmt.Println(sep)
+	if len(problems) == 0 {
+		fmt.Println("config is valid")
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Printf("ERROR: %s\n", p)
+	}
+	fmt.Println(sep)
+	return fmt.Errorf("found %d problem(s) in %s", len(problems), filename)
+}
+
+func validateGeneral(conf *Config) []string {
+	problems := []string{}
+	if conf.User == "" {
+		problems = append(problems, "user is empty, it is needed to name your signatures")
+	}
+	if conf.KeyringBackend == "" {
+		problems = append(problems, "keyringbackend is empty")
+	}
+	if conf.AWS.Bucket == "" {
+		problems = append(problems, "aws bucket is empty")
+	}
+	return problems
+}
+
+func validateKeys(conf *Config) []string {
+	problems := []string{}
+	seen := map[string]bool{}
+	for i, key := range conf.Keys {
+		if key.Name == "" {
+			problems = append(problems, fmt.Sprintf("keys[%d] has no name", i))
+			continue
+		}
+		if seen[key.Name] {
+			problems = append(problems, fmt.Sprintf("key %s is defined more than once", key.Name))
+		}
+		seen[key.Name] = true
+
+		if _, _, err := bech32.DecodeAndConvert(key.Address); err != nil {
+			problems = append(problems, fmt.Sprintf("key %s has an invalid bech32 address %q: %s", key.Name, key.Address, err))
+		}
+		if key.LocalName == "" {
+			problems = append(problems, fmt.Sprintf("key %s has an empty localname, broadcast will require --key", key.Name))
+		}
+	}
+	return problems
+}
+
+func validateChains(conf *Config) []string {
+	problems := []string{}
+	seen := map[string]bool{}
+	for i, chain := range conf.Chains {
+		if chain.Name == "" {
+			problems = append(problems, fmt.Sprintf("chains[%d] has no name", i))
+			continue
+		}
+		if seen[chain.Name] {
+			problems = append(problems, fmt.Sprintf("chain %s is defined more than once", chain.Name))
+		}
+		seen[chain.Name] = true
+
+		if chain.ID == "" {
+			problems = append(problems, fmt.Sprintf("chain %s has no id", chain.Name))
+		}
+
+		if chain.Prefix == "" {
+			problems = append(problems, fmt.Sprintf("chain %s has no prefix", chain.Name))
+		} else {
+			for _, key := range conf.Keys {
+				if _, err := bech32ify(key.Address, chain.Prefix); err != nil {
+					problems = append(problems, fmt.Sprintf("cannot convert address of key %s to prefix %s of chain %s: %s", key.Name, chain.Prefix, chain.Name, err))
+					break
+				}
+			}
+		}
+
+		if chain.GasPrice != "" {
+			if _, err := sdk.ParseDecCoin(chain.GasPrice); err != nil {
+				problems = append(problems, fmt.Sprintf("chain %s has an invalid gasprice %q", chain.Name, chain.GasPrice))
+			}
+		}
+
+		if chain.Binary == "" {
+			problems = append(problems, fmt.Sprintf("chain %s has no binary", chain.Name))
+			continue
+		}
+		if _, err := exec.LookPath(chain.Binary); err != nil {
+			problems = append(problems, fmt.Sprintf("binary %s of chain %s is not on the PATH", chain.Binary, chain.Name))
+			continue
+		}
+
+		if flagOffline {
+			continue
+		}
+		problems = append(problems, validateBinary(chain)...)
+		problems = append(problems, validateEndpoints(chain)...)
+	}
+	return problems
+}
+
+// check the binary of a chain reports its chain-id, by asking the rpc endpoint through it
+func validateBinary(chain Chain) []string {
+	if chain.RPC == "" {
+		fmt.Printf("chain %s has no rpc endpoint, skipping the %s status check\n", chain.Name, chain.Binary)
+		return nil
+	}
+	// some versions print the status to stderr
+	b, err := exec.Command(chain.Binary, "status", "--node", chain.RPC).CombinedOutput()
+	if err != nil {
+		return []string{fmt.Sprintf("%s status --node %s of chain %s failed: %s: %s", chain.Binary, chain.RPC, chain.Name, err, bytes.TrimSpace(b))}
+	}
+	network, err := parseBinaryStatus(b)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot read the chain-id from %s status of chain %s: %s", chain.Binary, chain.Name, err)}
+	}
+	if network != chain.ID {
+		return []string{fmt.Sprintf("%s of chain %s reports chain-id %s, expected %s", chain.Binary, chain.Name, network, chain.ID)}
+	}
+	fmt.Printf("chain %s: %s reports chain-id %s\n", chain.Name, chain.Binary, network)
+	return nil
+}
+
+// the chain-id in the output of `<binary> status`, which is NodeInfo before cosmos-sdk v0.50 and node_info since
+func parseBinaryStatus(b []byte) (string, error) {
+	start := bytes.IndexByte(b, '{')
+	if start < 0 {
+		return "", fmt.Errorf("no json in the output")
+	}
+	type nodeInfo struct {
+		Network string `json:"network"`
+	}
+	var status struct {
+		NodeInfo    nodeInfo `json:"NodeInfo"`
+		NodeInfoNew nodeInfo `json:"node_info"`
+	}
+	if err := json.Unmarshal(b[start:], &status); err != nil {
+		return "", err
+	}
+	if status.NodeInfo.Network != "" {
+		return status.NodeInfo.Network, nil
+	}
+	if status.NodeInfoNew.Network != "" {
+		return status.NodeInfoNew.Network, nil
+	}
+	return "", fmt.Errorf("no network in the node info")
+}
+
+// check the endpoints of a chain are reachable and on its chain-id
+func validateEndpoints(chain Chain) []string {
+	problems := []string{}
+	client := newChainClient(chain)
+
+	if chain.RPC == "" {
+		fmt.Printf("chain %s has no rpc endpoint, skipping rpc checks\n", chain.Name)
+	} else if network, err := client.Network(); err != nil {
+		problems = append(problems, fmt.Sprintf("rpc endpoint %s of chain %s is not reachable: %s", chain.RPC, chain.Name, err))
+	} else if network != chain.ID {
+		problems = append(problems, fmt.Sprintf("rpc endpoint %s of chain %s reports chain-id %s, expected %s", chain.RPC, chain.Name, network, chain.ID))
+	} else {
+		fmt.Printf("chain %s: %s reports chain-id %s\n", chain.Name, chain.RPC, network)
+	}
+
+	if chain.REST == "" {
+		fmt.Printf("WARNING: chain %s has no rest endpoint, its accounts and txs are queried with the binary and rewards, balances, gov pending and grants skip it\n", chain.Name)
+	} else if nodeInfo, err := client.NodeInfo(); err != nil {
+		problems = append(problems, fmt.Sprintf("rest endpoint %s of chain %s is not reachable: %s", chain.REST, chain.Name, err))
+	} else if network := nodeInfo.DefaultNodeInfo.Network; network != chain.ID {
+		problems = append(problems, fmt.Sprintf("rest endpoint %s of chain %s reports chain-id %s, expected %s", chain.REST, chain.Name, network, chain.ID))
+	} else {
+		fmt.Printf("chain %s: %s reports chain-id %s\n", chain.Name, chain.REST, network)
+	}
+
+	if chain.GRPC != "" {
+		if err := client.DialGRPC(); err != nil {
+			problems = append(problems, fmt.Sprintf("grpc endpoint %s of chain %s is not reachable: %s", chain.GRPC, chain.Name, err))
+		} else {
+			fmt.Printf("chain %s: %s is reachable\n", chain.Name, chain.GRPC)
+		}
+	}
+	return problems
+}
+
+// check we can write to and delete from the bucket
+func validateBucket(conf *Config) []string {
+	if conf.AWS.Bucket == "" {
+		return nil
+	}
+
+	sess := awsSession(conf.AWS)
+	probe := fmt.Sprintf(validateProbeObject, conf.User)
+	probeBytes := []byte(time.Now().UTC().Format(time.RFC3339))
+
+	if err := awsUpload(sess, conf.AWS, "", probe, probeBytes); err != nil {
+		return []string{fmt.Sprintf("cannot write to bucket %s: %s", conf.AWS.Bucket, err)}
+	}
+	if err := awsDelete(sess, conf.AWS, probe); err != nil {
+		return []string{fmt.Sprintf("cannot delete from bucket %s, please remove %s manually: %s", conf.AWS.Bucket, probe, err)}
+	}
+	fmt.Printf("bucket %s: write and delete ok\n", conf.AWS.Bucket)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBinaryStatus(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "before v0.50", output: `{"NodeInfo":{"network":"cosmoshub-4","version":"0.34.24"},"SyncInfo":{}}`, want: "cosmoshub-4"},
+		{name: "since v0.50", output: `{"node_info":{"network":"osmosis-1"},"sync_info":{}}`, want: "osmosis-1"},
+		{name: "after a log line", output: "some warning\n{\"NodeInfo\":{\"network\":\"juno-1\"}}\n", want: "juno-1"},
+		{name: "no json", output: "Error: post failed: connection refused\n", wantErr: true},
+		{name: "no network", output: `{"SyncInfo":{}}`, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseBinaryStatus([]byte(tc.output))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// a fake binary printing its arguments to args.txt and the status of a node on chainID
+func newTestBinary(t *testing.T, chainID string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args.txt")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\necho '{\"NodeInfo\":{\"network\":\"" + chainID + "\"}}' >&2\n"
+	binary := filepath.Join(dir, "testd")
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return binary, argsFile
+}
+
+func TestValidateBinary(t *testing.T) {
+	binary, argsFile := newTestBinary(t, "cosmoshub-4")
+
+	chain := Chain{Name: "cosmos", Binary: binary, ID: "cosmoshub-4", RPC: "http://localhost:26657"}
+	if problems := validateBinary(chain); len(problems) != 0 {
+		t.Fatalf("got problems %v", problems)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "status --node http://localhost:26657" {
+		t.Fatalf("binary called with %q", got)
+	}
+
+	chain.ID = "theta-testnet-001"
+	if problems := validateBinary(chain); len(problems) != 1 || !strings.Contains(problems[0], "reports chain-id cosmoshub-4, expected theta-testnet-001") {
+		t.Fatalf("got problems %v", problems)
+	}
+
+	chain.RPC = ""
+	if problems := validateBinary(chain); len(problems) != 0 {
+		t.Fatalf("got problems %v without an rpc endpoint", problems)
+	}
+}